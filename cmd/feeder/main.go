@@ -0,0 +1,448 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/cloud"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/amelendres/go-feeder/pkg/s3"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/watch"
+	"github.com/amelendres/go-feeder/pkg/web"
+)
+
+const (
+	devomAPIUrl  = "http://localhost:8030/api/v1"
+	devomTimeout = "30s"
+	s3Endpoint   = "https://s3.amazonaws.com"
+	s3Region     = "us-east-1"
+)
+
+// exit codes
+const (
+	exitOk      = 0
+	exitFailed  = 1
+	exitUsage   = 2
+	exitUnknown = 3
+)
+
+const usage = `Usage: feeder <command> [flags] <file>
+
+Commands:
+  parse     print the parsed items
+  validate  check the document has no unknown items
+  diff      print the changes an import would do
+  import    send the items to the devom API
+  watch     import the new and changed documents of a directory until interrupted,
+            writing a <document>.report.json next to each one
+
+The file is a local path or a file://, http(s)://, s3:// or gdrive:// url, set GOOGLE_API_KEY or GOOGLE_DRIVE_AUTH to read from Google Drive.
+A local directory, glob pattern as "2021/*.docx" or zip archive, filtered as "2021.zip!/*.docx", runs every document,
+importing each one to the plan its name starts with, its feeder.json manifest entry or the -plan one.
+It exits with 3 when the document has unknown items and with 1 on any other failure.
+`
+
+type options struct {
+	kind, planId, authorId, publisherId string
+	fieldMap, format                    string
+	upsert, json                        bool
+	interval, debounce                  time.Duration
+	file                                string
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitUsage)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		cancel()
+	}()
+
+	code := run(ctx, os.Args[1], os.Args[2:], os.Stdout)
+	cancel()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, cmd string, args []string, w io.Writer) int {
+	var command func(context.Context, options, io.Writer) int
+	switch cmd {
+	case "parse":
+		command = parse
+	case "validate":
+		command = validate
+	case "diff":
+		command = diff
+	case "import":
+		command = send
+	case "watch":
+		command = watchFolder
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(w, usage)
+		return exitOk
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		return exitUsage
+	}
+
+	opts, err := parseFlags(cmd, args)
+	if err != nil {
+		return exitUsage
+	}
+	return command(ctx, opts, w)
+}
+
+func parseFlags(cmd string, args []string) (options, error) {
+	var opts options
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	flags.StringVar(&opts.kind, "kind", "devotionals", "kind of feeds, "+strings.Join(devom.KindNames(), ", "))
+	flags.StringVar(&opts.planId, "plan", "", "destination plan id")
+	flags.StringVar(&opts.authorId, "author", "", "destination author id")
+	flags.StringVar(&opts.publisherId, "publisher", "", "destination publisher id")
+	flags.StringVar(&opts.fieldMap, "map", os.Getenv("DEVOTIONAL_FIELD_MAP"), "csv and json columns of the devotional keys, as day=Dia,title=Titulo")
+	flags.StringVar(&opts.format, "format", "", "rich text of the devotionals content from .docx, markdown or html, plain text by default")
+	flags.BoolVar(&opts.upsert, "upsert", false, "update the devotionals that already exist")
+	flags.BoolVar(&opts.json, "json", false, "print JSON instead of a table")
+	flags.DurationVar(&opts.interval, "interval", 5*time.Second, "watch polling interval")
+	flags.DurationVar(&opts.debounce, "debounce", 10*time.Second, "watch time a document must stay unchanged before importing it")
+	if err := flags.Parse(args); err != nil {
+		return opts, err
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "%s wants a single file, got %d\n", cmd, flags.NArg())
+		return opts, flag.ErrHelp
+	}
+	if err := feed.Format(opts.format).Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return opts, flag.ErrHelp
+	}
+	opts.file = flags.Arg(0)
+	return opts, nil
+}
+
+func parse(ctx context.Context, opts options, w io.Writer) int {
+	if fs.IsFolder(opts.file) {
+		return parseFolder(ctx, opts, w, false)
+	}
+	feeds, code := feedsOf(ctx, opts)
+	if feeds == nil {
+		return code
+	}
+	if opts.json {
+		printJSON(w, feeds)
+	} else {
+		printItems(w, feeds.Items)
+		printUnknownItems(w, feeds.UnknownItems)
+	}
+	return code
+}
+
+func validate(ctx context.Context, opts options, w io.Writer) int {
+	if fs.IsFolder(opts.file) {
+		return parseFolder(ctx, opts, w, true)
+	}
+	feeds, code := feedsOf(ctx, opts)
+	if feeds == nil {
+		return code
+	}
+	if opts.json {
+		printJSON(w, feeds.UnknownItems)
+	} else {
+		printUnknownItems(w, feeds.UnknownItems)
+		fmt.Fprintf(w, "%d items, %d unknown\n", len(feeds.Items), len(feeds.UnknownItems))
+	}
+	return code
+}
+
+func diff(ctx context.Context, opts options, w io.Writer) int {
+	svc, err := newServices(ctx, opts)
+	if err != nil {
+		return fail(err)
+	}
+	if fs.IsFolder(opts.file) {
+		report, err := svc.folders().Plan(ctx, opts.batchReq())
+		return printBatchReport(w, opts, report, err)
+	}
+	report, err := svc.sending().Plan(ctx, opts.sendReq())
+	return printReport(w, opts, report, err)
+}
+
+func send(ctx context.Context, opts options, w io.Writer) int {
+	svc, err := newServices(ctx, opts)
+	if err != nil {
+		return fail(err)
+	}
+	if fs.IsFolder(opts.file) {
+		report, err := svc.folders().Send(ctx, opts.batchReq())
+		return printBatchReport(w, opts, report, err)
+	}
+	report, err := svc.sending().Send(ctx, opts.sendReq())
+	return printReport(w, opts, report, err)
+}
+
+// watchFolder imports the documents of the directory as they change, until interrupted
+func watchFolder(ctx context.Context, opts options, w io.Writer) int {
+	svc, err := newServices(ctx, opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	log.Printf("watching %s", opts.file)
+	watcher := watch.NewWatcher(opts.batchReq(), feeding.NewService(svc.feeder()), svc.sending(), watch.WithInterval(opts.interval), watch.WithDebounce(opts.debounce))
+	if err := watcher.Run(ctx); err != nil && ctx.Err() == nil {
+		return fail(err)
+	}
+	return exitOk
+}
+
+// feedsOf parses the file, the exit code tells whether it has unknown items
+func feedsOf(ctx context.Context, opts options) (*feed.ParsedItems, int) {
+	svc, err := newServices(ctx, opts)
+	if err != nil {
+		return nil, fail(err)
+	}
+	feeds, err := feeding.NewService(svc.feeder()).Feeds(ctx, feeding.FeedReq(opts.sendReq()))
+	if err != nil {
+		return nil, fail(err)
+	}
+	if len(feeds.UnknownItems) > 0 {
+		return feeds, exitUnknown
+	}
+	return feeds, exitOk
+}
+
+// parseFolder parses every document of the directory or zip archive, validate prints only the unknown items
+func parseFolder(ctx context.Context, opts options, w io.Writer, validate bool) int {
+	svc, err := newServices(ctx, opts)
+	if err != nil {
+		return fail(err)
+	}
+	d := feed.NewDestination(opts.planId, opts.publisherId, opts.authorId)
+	d.Upsert = opts.upsert
+	d.Format = feed.Format(opts.format)
+	feeds, err := fs.Feeds(ctx, svc.parser(), d, opts.file)
+	if err != nil {
+		return fail(err)
+	}
+
+	code := exitOk
+	for _, ff := range feeds {
+		if ff.Error != "" {
+			log.Printf("%s: %s: %s", ff.File, ff.Code, ff.Error)
+			code = exitFailed
+			continue
+		}
+		if len(ff.Feeds.UnknownItems) > 0 && code == exitOk {
+			code = exitUnknown
+		}
+	}
+	if opts.json {
+		printJSON(w, feeds)
+		return code
+	}
+	for _, ff := range feeds {
+		if ff.Feeds == nil {
+			continue
+		}
+		fmt.Fprintf(w, "== %s\n", ff.File)
+		if !validate {
+			printItems(w, ff.Feeds.Items)
+		}
+		printUnknownItems(w, ff.Feeds.UnknownItems)
+		fmt.Fprintf(w, "%d items, %d unknown\n", len(ff.Feeds.Items), len(ff.Feeds.UnknownItems))
+	}
+	return code
+}
+
+func printBatchReport(w io.Writer, opts options, report *batch.Report, err error) int {
+	if report == nil {
+		return fail(err)
+	}
+	code := exitOk
+	for _, doc := range report.Documents {
+		if doc.Error == "" {
+			continue
+		}
+		if len(doc.UnknownItems) == 0 {
+			code = exitFailed
+		} else if code == exitOk {
+			code = exitUnknown
+		}
+	}
+	if opts.json {
+		printJSON(w, report)
+	} else {
+		printDocuments(w, report)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return code
+}
+
+func printReport(w io.Writer, opts options, report *feed.Report, err error) int {
+	var unknown *sending.UnknownFeedError
+	if errors.As(err, &unknown) {
+		printUnknownItems(w, unknown.UnknownItems)
+		return exitUnknown
+	}
+	if report != nil {
+		if opts.json {
+			printJSON(w, report)
+		} else {
+			printChanges(w, report)
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return exitOk
+}
+
+func fail(err error) int {
+	log.Printf("%s: %v", feed.Code(err), err)
+	return exitFailed
+}
+
+func (opts options) sendReq() sending.SendReq {
+	return sending.SendReq{
+		PlanId:      opts.planId,
+		AuthorId:    opts.authorId,
+		PublisherId: opts.publisherId,
+		FileUrl:     opts.file,
+		Upsert:      opts.upsert,
+		Format:      feed.Format(opts.format),
+	}
+}
+
+func (opts options) batchReq() batch.Req {
+	return batch.Req{
+		FolderUrl:   opts.file,
+		PlanId:      opts.planId,
+		AuthorId:    opts.authorId,
+		PublisherId: opts.publisherId,
+		Upsert:      opts.upsert,
+		Format:      feed.Format(opts.format),
+	}
+}
+
+// services build the parsers and senders of the kind of feeds of the options, on a single devom API
+type services struct {
+	kind      devom.Kind
+	api       devom.API
+	mapping   devom.FieldMapping
+	providers []feed.FileProvider
+}
+
+func newServices(ctx context.Context, opts options) (*services, error) {
+	kind, err := devom.KindOf(opts.kind)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := devom.ParseFieldMapping(opts.fieldMap)
+	if err != nil {
+		return nil, err
+	}
+	api, err := newAPI()
+	if err != nil {
+		return nil, err
+	}
+	providers, err := newProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &services{kind: kind, api: api, mapping: mapping, providers: providers}, nil
+}
+
+func (svc *services) parser() feed.Parser {
+	return svc.kind.NewParser(svc.api, svc.mapping)
+}
+
+func (svc *services) feeder() feed.Feeder {
+	return feed.NewFeeder(svc.parser(), svc.providers)
+}
+
+func (svc *services) sending() sending.Service {
+	return sending.NewService(svc.kind.NewSender(svc.api), svc.feeder())
+}
+
+// folders imports the documents of local directories and zip archives
+func (svc *services) folders() batch.Service {
+	return batch.NewService(svc.sending, []feed.Folder{fs.NewFolder()})
+}
+
+func newProviders(ctx context.Context) ([]feed.FileProvider, error) {
+	httpOpts, err := web.HeaderOptions(os.Getenv("HTTP_FILE_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid HTTP_FILE_HEADERS: %w", err)
+	}
+	httpOpts = append(httpOpts, web.HostOptions(os.Getenv("HTTP_FILE_HOSTS"))...)
+	if private := os.Getenv("HTTP_FILE_PRIVATE"); private != "" {
+		ok, err := strconv.ParseBool(private)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP_FILE_PRIVATE <%s>", private)
+		}
+		if ok {
+			httpOpts = append(httpOpts, web.WithPrivateAddresses())
+		}
+	}
+	s3p := s3.NewFileProvider(
+		s3.WithEndpoint(getEnv("S3_ENDPOINT", s3Endpoint)),
+		s3.WithRegion(getEnv("S3_REGION", s3Region)),
+		s3.WithCredentials(os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY"), os.Getenv("S3_SESSION_TOKEN")),
+	)
+	providers := []feed.FileProvider{fs.NewFileProvider(), web.NewFileProvider(httpOpts...), s3p}
+	driveAuth, err := cloud.DriveAuthFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if !driveAuth.Disabled() {
+		driveService, err := cloud.NewDriveService(ctx, driveAuth)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, cloud.NewGDFileProvider(driveService))
+	}
+	return providers, nil
+}
+
+func newAPI() (devom.API, error) {
+	timeout, err := time.ParseDuration(getEnv("DEVOM_API_TIMEOUT", devomTimeout))
+	if err != nil {
+		return devom.API{}, fmt.Errorf("invalid DEVOM_API_TIMEOUT: %w", err)
+	}
+	opts := []devom.Option{devom.WithTimeout(timeout)}
+	if token := os.Getenv("DEVOM_API_TOKEN"); token != "" {
+		opts = append(opts, devom.WithBearerToken(token))
+	}
+	return *devom.NewAPI(getEnv("DEVOM_API_URL", devomAPIUrl), opts...), nil
+}
+
+func getEnv(key, fallback string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		value = fallback
+	}
+	return value
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	planId      = "23a63256-f264-4d94-b7ed-8ce60f744ae3"
+	authorId    = "eeef78ed-043e-40db-9eae-8a0d77950ceb"
+	publisherId = "e5f12936-1339-4dbc-b339-fb0ba42b13a9"
+	okDocx      = "../../internal/devom/_test_devotionals-ok.docx"
+	koDocx      = "../../internal/devom/_test_devotionals-ko.docx"
+	okMarkdown  = "../../internal/devom/_test_devotionals-ok.md"
+)
+
+// newDevom serves the devom API of the commands, with an empty plan
+func newDevom(t *testing.T) *devomtest.Server {
+	t.Helper()
+	srv := devomtest.NewServer()
+	t.Cleanup(srv.Close)
+	srv.SeedPlan(devom.Plan{Id: planId, Title: "2021", AuthorId: authorId})
+
+	url, ok := os.LookupEnv("DEVOM_API_URL")
+	os.Setenv("DEVOM_API_URL", srv.URL)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("DEVOM_API_URL", url)
+			return
+		}
+		os.Unsetenv("DEVOM_API_URL")
+	})
+	return srv
+}
+
+func TestRun(t *testing.T) {
+	newDevom(t)
+	dest := []string{"-plan", planId, "-author", authorId, "-publisher", publisherId}
+
+	for _, tt := range []struct {
+		name string
+		cmd  string
+		args []string
+		code int
+		out  string
+	}{
+		{"it prints the usage", "help", nil, exitOk, "Usage: feeder"},
+		{"it fails with an unknown command", "export", []string{okDocx}, exitUsage, ""},
+		{"it wants a single file", "parse", nil, exitUsage, ""},
+		{"it fails with an unknown format", "parse", []string{"-format", "rtf", okDocx}, exitUsage, ""},
+		{"it fails with an unknown kind", "parse", []string{"-kind", "songs", okDocx}, exitFailed, ""},
+		{"it fails with a missing file", "parse", []string{"missing.docx"}, exitFailed, ""},
+		{"it prints the parsed items", "parse", []string{okDocx}, exitOk, "BIBLE_READING"},
+		{"it prints the parsed items as JSON", "parse", []string{"-json", okDocx}, exitOk, `"Items": [`},
+		{"it parses with the parser of the kind", "parse", []string{"-kind", "markdown-devotionals", okMarkdown}, exitOk, "PASSAGE_TEXT"},
+		{"it validates a document", "validate", []string{okDocx}, exitOk, "15 items, 0 unknown"},
+		{"it tells the unknown items", "validate", []string{koDocx}, exitUnknown, "UNKNOWN ITEM"},
+		{"it validates every document of a folder", "validate", []string{"../../internal/devom/_test_devotionals-*.docx"}, exitUnknown, "== _test_devotionals-ok.docx\n15 items, 0 unknown"},
+		{"it fails with a format the kind does not write on a folder", "parse", []string{"-kind", "topics", "-format", "html", "../../internal/devom/_test_topics-o?.xlsx"}, exitFailed, ""},
+		{"it prints the changes of an import", "diff", append(dest, okDocx), exitOk, "15 items, 0 failed"},
+		{"it prints the unknown items of an import", "diff", append(dest, koDocx), exitUnknown, "UNKNOWN ITEM"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			code := run(context.Background(), tt.cmd, tt.args, &out)
+
+			assert.Equal(t, tt.code, code)
+			assert.Contains(t, out.String(), tt.out)
+		})
+	}
+}
+
+func TestRun_Import(t *testing.T) {
+	srv := newDevom(t)
+	dest := []string{"-plan", planId, "-author", authorId, "-publisher", publisherId}
+
+	t.Run("it imports a document", func(t *testing.T) {
+		var out bytes.Buffer
+
+		code := run(context.Background(), "import", append(dest, okDocx), &out)
+
+		assert.Equal(t, exitOk, code)
+		assert.Contains(t, out.String(), "15 items, 0 failed")
+		assert.Len(t, srv.DailyDevotionals(planId), 15)
+	})
+
+	t.Run("it imports the documents of a folder to the plan of their names", func(t *testing.T) {
+		dir := t.TempDir()
+		b, err := ioutil.ReadFile(okDocx)
+		assert.Nil(t, err)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, planId+" Devotionals.docx"), b, 0644))
+		var out bytes.Buffer
+
+		code := run(context.Background(), "import", []string{"-author", authorId, "-publisher", publisherId, "-upsert", "-json", dir}, &out)
+
+		assert.Equal(t, exitOk, code)
+		assert.Contains(t, out.String(), `"planId": "`+planId+`"`)
+		assert.Contains(t, out.String(), `"unchanged": 15`)
+	})
+}
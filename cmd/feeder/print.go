@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+)
+
+const maxCellWidth = 40
+
+func printJSON(w io.Writer, v interface{}) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// printItems prints a row by item and a column by item field
+func printItems(w io.Writer, items []feed.Item) {
+	if len(items) == 0 {
+		return
+	}
+	var keys []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		for key := range item {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(keys, "\t")))
+	for _, item := range items {
+		cells := make([]string, len(keys))
+		for i, key := range keys {
+			cells[i] = cell(item[key])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+func printUnknownItems(w io.Writer, items []feed.UnknownItem) {
+	if len(items) == 0 {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "UNKNOWN ITEM\tERROR")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\n", cell(strings.Join(item.Item, " ")), item.ItemError)
+	}
+	tw.Flush()
+}
+
+func printChanges(w io.Writer, report *feed.Report) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OUTCOME\tACTION\tKIND\tDAY\tTITLE\tDETAIL")
+	for _, item := range report.Items {
+		if len(item.Changes) == 0 {
+			fmt.Fprintf(tw, "%s\t\t\t\t%s\t%s\n", item.Outcome, cell(item.Item["title"]), item.Error)
+		}
+		for _, c := range item.Changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", item.Outcome, c.Action, c.Kind, c.Day, cell(c.Title), c.Detail)
+		}
+		if item.Error != "" && len(item.Changes) > 0 {
+			fmt.Fprintf(tw, "%s\t\t\t\t%s\t%s\n", item.Outcome, cell(item.Item["title"]), item.Error)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d items, %d failed\n", len(report.Items), report.Count(feed.OutcomeFailed))
+}
+
+func printDocuments(w io.Writer, report *batch.Report) {
+	for _, doc := range report.Documents {
+		fmt.Fprintf(w, "== %s %s\n", doc.File, doc.PlanId)
+		if doc.Report != nil {
+			printChanges(w, doc.Report)
+		}
+		printUnknownItems(w, doc.UnknownItems)
+		if doc.Error != "" {
+			fmt.Fprintf(w, "%s: %s\n", doc.Code, doc.Error)
+		}
+	}
+	if len(report.Ignored) > 0 {
+		fmt.Fprintf(w, "ignored %s\n", strings.Join(report.Ignored, ", "))
+	}
+	fmt.Fprintf(w, "%d documents, %d failed\n", len(report.Documents), report.Failed)
+}
+
+// cell fits a value in a single table line
+func cell(v string) string {
+	v = strings.Join(strings.Fields(v), " ")
+	if r := []rune(v); len(r) > maxCellWidth {
+		return string(r[:maxCellWidth-3]) + "..."
+	}
+	return v
+}
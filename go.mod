@@ -14,5 +14,8 @@ require (
 	github.com/stretchr/testify v1.6.1
 	github.com/unidoc/unioffice v1.4.0
 	github.com/xuri/excelize/v2 v2.4.1
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/net v0.0.0-20210726213435-c6fcb2dbf985
+	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	google.golang.org/api v0.29.0
 )
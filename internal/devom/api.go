@@ -2,34 +2,119 @@ package devom
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
 )
 
 var (
 	ErrGettingResource = func(want, got int) error {
-		return fmt.Errorf("fails getting resource, unexpected response status, want %d but got %d", want, got)
+		return feed.NewError(statusCode(got), fmt.Errorf("fails getting resource, unexpected response status, want %d but got %d", want, got))
 	}
 	ErrCreatingResource = func(want, got int) error {
-		return fmt.Errorf("fails creating resource, unexpected response status, want %d but got %d", want, got)
+		return feed.NewError(statusCode(got), fmt.Errorf("fails creating resource, unexpected response status, want %d but got %d", want, got))
+	}
+	ErrUpdatingResource = func(want, got int) error {
+		return feed.NewError(statusCode(got), fmt.Errorf("fails updating resource, unexpected response status, want %d but got %d", want, got))
+	}
+	ErrRequestingResource = func(err error) error {
+		return feed.NewError(feed.ErrCodeUnavailable, err)
 	}
 )
 
+const (
+	defaultTimeout = 30 * time.Second
+	defaultRetries = 3
+	defaultBackoff = 500 * time.Millisecond
+	defaultMaxWait = 30 * time.Second
+)
+
 type API struct {
-	apiUrl string
+	apiUrl  string
+	client  *http.Client
+	headers map[string]string
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	maxWait time.Duration
+}
+
+type Option func(*API)
+
+// WithHTTPClient sends the requests through a custom http client
+func WithHTTPClient(c *http.Client) Option {
+	return func(a *API) { a.client = c }
+}
+
+// WithBearerToken authenticates the requests with a bearer token
+func WithBearerToken(token string) Option {
+	return WithHeader("authorization", "Bearer "+token)
+}
+
+// WithAPIKey authenticates the requests with an api key header
+func WithAPIKey(header, key string) Option {
+	return WithHeader(header, key)
+}
+
+func WithHeader(key, value string) Option {
+	return func(a *API) { a.headers[key] = value }
+}
+
+// WithTimeout limits the time of each request attempt, 0 does not limit it
+func WithTimeout(d time.Duration) Option {
+	return func(a *API) { a.timeout = d }
+}
+
+// WithRetry retries a failed request up to retries times, waiting an exponential backoff
+// from the given one or the devom Retry-After
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(a *API) { a.retries, a.backoff = retries, backoff }
+}
+
+// WithMaxWait limits the wait before a retry, the request fails when devom asks to wait longer
+func WithMaxWait(d time.Duration) Option {
+	return func(a *API) { a.maxWait = d }
 }
 
-func NewAPI(apiUrl string) *API {
-	return &API{apiUrl: apiUrl}
+func NewAPI(apiUrl string, opts ...Option) *API {
+	a := &API{
+		apiUrl:  apiUrl,
+		client:  http.DefaultClient,
+		headers: make(map[string]string),
+		timeout: defaultTimeout,
+		retries: defaultRetries,
+		backoff: defaultBackoff,
+		maxWait: defaultMaxWait,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // Creates Devotional
-func (a *API) createDevotional(dev Devotional) error {
+func (a *API) createDevotional(ctx context.Context, dev Devotional) error {
 	endpoint := fmt.Sprintf("%s/devotionals", a.apiUrl)
-	_, err := post(endpoint, dev)
+	_, err := a.post(ctx, endpoint, dev)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Updates Devotional
+func (a *API) updateDevotional(ctx context.Context, dev Devotional) error {
+	endpoint := fmt.Sprintf("%s/devotionals/%s", a.apiUrl, dev.Id)
+	_, err := a.put(ctx, endpoint, dev)
 	if err != nil {
 		return err
 	}
@@ -37,22 +122,22 @@ func (a *API) createDevotional(dev Devotional) error {
 	return nil
 }
 
-func (a *API) getDevotionals(authorId string) ([]*Devotional, error) {
+func (a *API) getDevotionals(ctx context.Context, authorId string) ([]*Devotional, error) {
 	endpoint := fmt.Sprintf("%s/devotionals?authorId=%s", a.apiUrl, authorId)
-	resp, err := get(endpoint)
+	body, err := a.get(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
-	items, err := newDevotionalsFromJSON(resp.Body)
+	items, err := newDevotionalsFromJSON(body)
 	if err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
-func (a *API) addDevotionalTopic(req AddDevotionalTopicReq) error {
+func (a *API) addDevotionalTopic(ctx context.Context, req AddDevotionalTopicReq) error {
 	endpoint := fmt.Sprintf("%s/devotionals/%s/topics/add", a.apiUrl, req.DevotionalId)
-	_, err := post(endpoint, req)
+	_, err := a.post(ctx, endpoint, req)
 	if err != nil {
 		return err
 	}
@@ -61,9 +146,9 @@ func (a *API) addDevotionalTopic(req AddDevotionalTopicReq) error {
 }
 
 // Creates Plan
-func (a *API) createPlan(plan Plan) error {
+func (a *API) createPlan(ctx context.Context, plan Plan) error {
 	endpoint := fmt.Sprintf("%s/yearly-plans", a.apiUrl)
-	_, err := post(endpoint, plan)
+	_, err := a.post(ctx, endpoint, plan)
 	if err != nil {
 		return err
 	}
@@ -71,9 +156,9 @@ func (a *API) createPlan(plan Plan) error {
 	return nil
 }
 
-func (a *API) addDailyDevotional(req AddDailyDevotionalReq) error {
+func (a *API) addDailyDevotional(ctx context.Context, req AddDailyDevotionalReq) error {
 	endpoint := fmt.Sprintf("%s/yearly-plans/%s/devotionals", a.apiUrl, req.PlanId)
-	_, err := post(endpoint, req)
+	_, err := a.post(ctx, endpoint, req)
 	if err != nil {
 		return err
 	}
@@ -81,9 +166,9 @@ func (a *API) addDailyDevotional(req AddDailyDevotionalReq) error {
 	return nil
 }
 
-func (a *API) addNextDevotional(body AddNextDevotionalReq) error {
+func (a *API) addNextDevotional(ctx context.Context, body AddNextDevotionalReq) error {
 	endpoint := fmt.Sprintf("%s/yearly-plans/%s/devotionals", a.apiUrl, body.PlanId)
-	_, err := post(endpoint, body)
+	_, err := a.post(ctx, endpoint, body)
 	if err != nil {
 		return err
 	}
@@ -91,19 +176,19 @@ func (a *API) addNextDevotional(body AddNextDevotionalReq) error {
 	return nil
 }
 
-func (a *API) getPlans(authorId string) ([]*Plan, error) {
+func (a *API) getPlans(ctx context.Context, authorId string) ([]*Plan, error) {
 	endpoint := fmt.Sprintf("%s/yearly-plans?authorId=%s", a.apiUrl, authorId)
-	resp, err := get(endpoint)
+	body, err := a.get(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	plans, err := newPlansFromJSON(resp.Body)
+	plans, err := newPlansFromJSON(body)
 	if err != nil {
 		return nil, err
 	}
 	for _, item := range plans {
-		dailyDevotionals, err := a.getDailyDevotionals(item.Id)
+		dailyDevotionals, err := a.getDailyDevotionals(ctx, item.Id)
 		if err != nil {
 			log.Println(err)
 			continue
@@ -117,19 +202,19 @@ func (a *API) getPlans(authorId string) ([]*Plan, error) {
 	return plans, nil
 }
 
-func (a *API) getPlan(planId string) (*Plan, error) {
+func (a *API) getPlan(ctx context.Context, planId string) (*Plan, error) {
 	endpoint := fmt.Sprintf("%s/yearly-plans/%s", a.apiUrl, planId)
-	resp, err := get(endpoint)
+	body, err := a.get(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	plan, err := newPlanFromJSON(resp.Body)
+	plan, err := newPlanFromJSON(body)
 	if err != nil {
 		return nil, err
 	}
 
-	dailyDevotionals, err := a.getDailyDevotionals(plan.Id)
+	dailyDevotionals, err := a.getDailyDevotionals(ctx, plan.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -142,14 +227,14 @@ func (a *API) getPlan(planId string) (*Plan, error) {
 	return plan, nil
 }
 
-func (a *API) getDailyDevotionals(planId string) ([]*DailyDevotional, error) {
+func (a *API) getDailyDevotionals(ctx context.Context, planId string) ([]*DailyDevotional, error) {
 	endpoint := fmt.Sprintf("%s/yearly-plans/%s/devotionals", a.apiUrl, planId)
-	resp, err := get(endpoint)
+	body, err := a.get(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	dailyDevotionals, err := newDailyDevotionalsFromJSON(resp.Body)
+	dailyDevotionals, err := newDailyDevotionalsFromJSON(body)
 	if err != nil {
 		return nil, err
 	}
@@ -158,9 +243,9 @@ func (a *API) getDailyDevotionals(planId string) ([]*DailyDevotional, error) {
 }
 
 // Creates Topic
-func (a *API) createTopic(topic Topic) error {
+func (a *API) createTopic(ctx context.Context, topic Topic) error {
 	endpoint := fmt.Sprintf("%s/categories", a.apiUrl)
-	_, err := post(endpoint, topic)
+	_, err := a.post(ctx, endpoint, topic)
 	if err != nil {
 		return err
 	}
@@ -168,13 +253,13 @@ func (a *API) createTopic(topic Topic) error {
 	return nil
 }
 
-func (a *API) getTopics() ([]*Topic, error) {
+func (a *API) getTopics(ctx context.Context) ([]*Topic, error) {
 	endpoint := fmt.Sprintf("%s/categories", a.apiUrl)
-	resp, err := get(endpoint)
+	body, err := a.get(ctx, endpoint)
 	if err != nil {
 		return nil, err
 	}
-	topics, err := newTopicsFromJSON(resp.Body)
+	topics, err := newTopicsFromJSON(body)
 	if err != nil {
 		return nil, err
 	}
@@ -236,50 +321,148 @@ func newDailyDevotionalsFromJSON(rdr io.Reader) ([]*DailyDevotional, error) {
 	return items, err
 }
 
-func get(endpoint string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", endpoint, bytes.NewBuffer(nil))
-	if err != nil {
-		return nil, err
-	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		logRequestError(req, err)
-		return nil, err
+func (a *API) get(ctx context.Context, endpoint string) (io.Reader, error) {
+	return a.do(ctx, http.MethodGet, endpoint, nil, http.StatusOK)
+}
+
+func (a *API) post(ctx context.Context, endpoint string, obj interface{}) (io.Reader, error) {
+	return a.do(ctx, http.MethodPost, endpoint, obj, http.StatusCreated)
+}
+
+func (a *API) put(ctx context.Context, endpoint string, obj interface{}) (io.Reader, error) {
+	return a.do(ctx, http.MethodPut, endpoint, obj, http.StatusOK, http.StatusNoContent)
+}
+
+// do sends the request retrying the idempotent ones on failure, and any of them
+// when devom asks to (429, 503); it returns the whole response body
+func (a *API) do(ctx context.Context, method, endpoint string, obj interface{}, want ...int) (io.Reader, error) {
+	var body []byte
+	if obj != nil {
+		var err error
+		body, err = json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		err = ErrGettingResource(http.StatusOK, resp.StatusCode)
-		logRequestResponseError(req, resp, nil, err)
-		return nil, err
+	for attempt := 0; ; attempt++ {
+		respBody, status, wait, err := a.send(ctx, method, endpoint, body)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if wait == 0 {
+			wait = a.backoff * time.Duration(1<<uint(attempt))
+		}
+		retry := attempt < a.retries && wait <= a.maxWait &&
+			(isRetryable(status) || (isIdempotent(method) && (err != nil || status >= http.StatusInternalServerError)))
+		if err == nil && isWanted(status, want) {
+			return bytes.NewReader(respBody), nil
+		}
+		if !retry {
+			if err != nil {
+				return nil, ErrRequestingResource(err)
+			}
+			return nil, unexpectedStatus(method, want[0], status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
 	}
-	logRequest(req)
-	return resp, nil
 }
 
-func post(endpoint string, obj interface{}) (*http.Response, error) {
-	body, err := json.Marshal(obj)
+// send makes one attempt, the response body is always drained and closed
+func (a *API) send(ctx context.Context, method, endpoint string, body []byte) ([]byte, int, time.Duration, error) {
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return nil, 0, 0, err
+	}
+	if body != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+	for key, value := range a.headers {
+		req.Header.Set(key, value)
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
+	resp, err := a.client.Do(req)
 	if err != nil {
-		return nil, err
+		logRequestError(req, err)
+		return nil, 0, 0, err
 	}
+	defer resp.Body.Close()
 
-	resp, err := http.DefaultClient.Do(req)
+	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		logRequestError(req, err)
-		return nil, err
+		return nil, 0, 0, err
 	}
 
-	if resp.StatusCode != http.StatusCreated {
-		err = ErrCreatingResource(http.StatusCreated, resp.StatusCode)
-		logRequestResponseError(req, resp, body, err)
-		return nil, err
+	if resp.StatusCode >= http.StatusBadRequest {
+		logRequestResponseError(req, resp, body, fmt.Errorf("unexpected response status %d", resp.StatusCode))
+		return respBody, resp.StatusCode, retryAfter(resp), nil
 	}
 	logRequest(req)
-	return resp, nil
+	return respBody, resp.StatusCode, 0, nil
+}
+
+func unexpectedStatus(method string, want, got int) error {
+	switch method {
+	case http.MethodPost:
+		return ErrCreatingResource(want, got)
+	case http.MethodPut:
+		return ErrUpdatingResource(want, got)
+	}
+	return ErrGettingResource(want, got)
+}
+
+func isWanted(status int, want []int) bool {
+	for _, w := range want {
+		if status == w {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut
+}
+
+// isRetryable reports whether devom did not handle the request and asks to retry it
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// retryAfter is the wait devom asks for, in seconds or until an HTTP date
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("retry-after")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	at, err := http.ParseTime(value)
+	if err != nil || !at.After(time.Now()) {
+		return 0
+	}
+	return time.Until(at)
+}
+
+// statusCode classifies an unexpected devom response status, the retryable ones are unavailable
+func statusCode(status int) feed.ErrorCode {
+	if status >= http.StatusInternalServerError || isRetryable(status) {
+		return feed.ErrCodeUnavailable
+	}
+	return feed.ErrCodeRejected
 }
 
 func logRequestError(req *http.Request, err error) {
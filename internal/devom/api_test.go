@@ -0,0 +1,195 @@
+package devom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStatusServer(statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		status := statuses[len(statuses)-1]
+		if int(n) <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`[]`))
+	}))
+	return srv, &calls
+}
+
+func TestAPI_Retry(t *testing.T) {
+
+	t.Run("it retries an idempotent request", func(t *testing.T) {
+		srv, calls := newStatusServer(http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Millisecond))
+
+		_, err := api.getTopics(context.Background())
+
+		assert.Nil(t, err)
+		assert.Equal(t, int32(3), *calls)
+	})
+
+	t.Run("it gives up after the retries", func(t *testing.T) {
+		srv, calls := newStatusServer(http.StatusInternalServerError)
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Millisecond))
+
+		_, err := api.getTopics(context.Background())
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+		assert.Equal(t, int32(3), *calls)
+	})
+
+	t.Run("it does not retry a failed creation", func(t *testing.T) {
+		srv, calls := newStatusServer(http.StatusInternalServerError, http.StatusCreated)
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Millisecond))
+
+		err := api.createTopic(context.Background(), Topic{Id: "topic"})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, int32(1), *calls)
+	})
+
+	t.Run("it does not retry a cancelled request", func(t *testing.T) {
+		srv, calls := newStatusServer(http.StatusServiceUnavailable)
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Second))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		_, err := api.getTopics(ctx)
+
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, int32(1), *calls)
+	})
+
+	t.Run("it retries a creation when devom asks to", func(t *testing.T) {
+		srv, calls := newStatusServer(http.StatusTooManyRequests, http.StatusCreated)
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Millisecond))
+
+		err := api.createTopic(context.Background(), Topic{Id: "topic"})
+
+		assert.Nil(t, err)
+		assert.Equal(t, int32(2), *calls)
+	})
+
+	t.Run("it fails as unavailable when devom keeps asking to retry", func(t *testing.T) {
+		srv, calls := newStatusServer(http.StatusTooManyRequests)
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(1, time.Millisecond))
+
+		err := api.createTopic(context.Background(), Topic{Id: "topic"})
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+		assert.Equal(t, int32(2), *calls)
+	})
+
+	t.Run("it does not wait longer than the max wait", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("retry-after", "86400")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Millisecond), WithMaxWait(time.Second))
+
+		start := time.Now()
+		_, err := api.getTopics(context.Background())
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+		assert.Equal(t, int32(1), calls)
+		assert.True(t, time.Since(start) < time.Second)
+	})
+
+	t.Run("it waits until the date devom asks for", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("retry-after", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithRetry(2, time.Millisecond), WithMaxWait(time.Minute))
+
+		_, err := api.getTopics(context.Background())
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+		assert.Equal(t, int32(1), calls)
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"Wed, 21 Oct 2015 07:28:00 GMT": 0,
+		"soon":                          0,
+	} {
+		resp := &http.Response{Header: http.Header{"Retry-After": {value}}}
+
+		assert.Equal(t, want, retryAfter(resp), value)
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}}
+	wait := retryAfter(resp)
+	assert.True(t, wait > 59*time.Minute && wait <= time.Hour, wait)
+}
+
+func TestAPI_Options(t *testing.T) {
+
+	t.Run("it authenticates the requests", func(t *testing.T) {
+		var auth, key string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth, key = r.Header.Get("authorization"), r.Header.Get("x-api-key")
+			w.Write([]byte(`[]`))
+		}))
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithBearerToken("token"), WithAPIKey("x-api-key", "key"))
+
+		_, err := api.getTopics(context.Background())
+
+		assert.Nil(t, err)
+		assert.Equal(t, "Bearer token", auth)
+		assert.Equal(t, "key", key)
+	})
+
+	t.Run("it times out a slow request", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte(`[]`))
+		}))
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithTimeout(10*time.Millisecond), WithRetry(0, 0))
+
+		_, err := api.getTopics(context.Background())
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+	})
+
+	t.Run("it does not time out without timeout", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+			w.Write([]byte(`[]`))
+		}))
+		defer srv.Close()
+		api := NewAPI(srv.URL, WithTimeout(0), WithRetry(0, 0))
+
+		_, err := api.getTopics(context.Background())
+
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,78 @@
+package devom
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+var ErrMissingHeader = feed.NewError(feed.ErrCodeUnreadableFile, errors.New("the CSV file does not have a header"))
+
+type csvParser struct {
+	devotionalParser
+	mapping FieldMapping
+}
+
+// NewCSVDevotionalParser parses CSV exports with a header row, a devotional by row,
+// its columns are the ones of the destination mapping or the m ones, separated by commas or semicolons
+func NewCSVDevotionalParser(api API, m FieldMapping) feed.Parser {
+	return &csvParser{devotionalParser{api: api}, m}
+}
+
+func (cp *csvParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	empty := &feed.ParsedItems{UnknownItems: []feed.UnknownItem{}, Items: []feed.Item{}}
+	mapping, err := destinationMapping(cp.to, cp.mapping)
+	if err != nil {
+		return empty, err
+	}
+	br := bufio.NewReader(r)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = comma(br)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return empty, ErrReadingResource(err)
+	}
+	if len(rows) == 0 {
+		return empty, ErrMissingHeader
+	}
+
+	columns := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	records := make([]record, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		row := row
+		records = append(records, record{row, func() (feed.Item, error) {
+			return mapping.item(func(field string) string {
+				if i, ok := columns[field]; ok && i < len(row) {
+					return row[i]
+				}
+				return ""
+			})
+		}})
+	}
+	return cp.parse(ctx, records)
+}
+
+// comma is the separator of the header line, semicolons when it has no commas
+func comma(br *bufio.Reader) rune {
+	header, _ := br.Peek(br.Size())
+	if i := bytes.IndexByte(header, '\n'); i >= 0 {
+		header = header[:i]
+	}
+	if bytes.IndexByte(header, ',') < 0 && bytes.IndexByte(header, ';') >= 0 {
+		return ';'
+	}
+	return ','
+}
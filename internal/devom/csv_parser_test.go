@@ -0,0 +1,83 @@
+package devom_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+const csvDevotionals = "\xef\xbb\xbfDia;Titulo;Pasaje;Referencia;Lectura;Texto\n" +
+	"1;LA IMPORTANCIA DE LAS ESCRITURAS;“Lámpara es a mis pies tu palabra”;(Salmo 119:105);Lectura: Deut. 8:2-6.;\"El nuestro es un compromiso total; sin reservas.\"\n" +
+	"2;COMPARTIENDO ALEGREMENTE;“Pedro y Juan subían juntos al templo”;(Hechos 3:1);;Los creyentes se conocían.\n" +
+	"3;SIN CONTENIDO;“Jehová es mi fortaleza”;(Habacuc 3:19);;\n" +
+	"4;EXISTENTE;“Jehová es mi fortaleza”;(Habacuc 3:19);;Con alegría cantamos.\n"
+
+func TestCSVDevotionalParser(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+	srv.SeedDevotional(devom.Devotional{Id: "existing", Title: "EXISTENTE", AuthorId: "author"})
+
+	mapping, err := devom.ParseFieldMapping("day=Dia, title=Titulo, passage_text=Pasaje, passage_reference=Referencia, bible_reading=Lectura, content=Texto")
+	assert.Nil(t, err)
+	cp := devom.NewCSVDevotionalParser(*devom.NewAPI(srv.URL), mapping)
+	cp.Destination(feed.NewDestination("plan", "publisher", "author"))
+
+	t.Run("it maps the columns to the devotional keys", func(t *testing.T) {
+		feeds, err := cp.Parse(context.Background(), strings.NewReader(csvDevotionals))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(feeds.Items))
+		assert.Equal(t, feed.Item{
+			"day":               "1",
+			"title":             "LA IMPORTANCIA DE LAS ESCRITURAS",
+			"passage_text":      "“Lámpara es a mis pies tu palabra”",
+			"passage_reference": "(Salmo 119:105)",
+			"bible_reading":     "Lectura: Deut. 8:2-6.",
+			"content":           "El nuestro es un compromiso total; sin reservas.",
+		}, feeds.Items[0])
+	})
+
+	t.Run("it validates the devotionals as the Word documents", func(t *testing.T) {
+		feeds, err := cp.Parse(context.Background(), strings.NewReader(csvDevotionals))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(feeds.UnknownItems))
+		assert.Equal(t, devom.ErrFeedDoesNotHaveContent.Error(), feeds.UnknownItems[0].ItemError)
+		assert.Equal(t, devom.ErrTitleAlreadyExists("EXISTENTE").Error(), feeds.UnknownItems[1].ItemError)
+	})
+
+	t.Run("it fails with an invalid mapping", func(t *testing.T) {
+		_, err := devom.ParseFieldMapping("dia=Dia")
+
+		assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+	})
+
+	t.Run("it maps the columns with the mapping of the destination", func(t *testing.T) {
+		cp := devom.NewCSVDevotionalParser(*devom.NewAPI(srv.URL), devom.FieldMapping{})
+		d := feed.NewDestination("plan", "publisher", "author")
+		d.FieldMap = "day=Dia, title=Titulo, passage_text=Pasaje, passage_reference=Referencia, bible_reading=Lectura, content=Texto"
+		cp.Destination(d)
+
+		feeds, err := cp.Parse(context.Background(), strings.NewReader(csvDevotionals))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(feeds.Items))
+		assert.Equal(t, "LA IMPORTANCIA DE LAS ESCRITURAS", feeds.Items[0]["title"])
+	})
+
+	t.Run("it fails with an invalid mapping of the destination", func(t *testing.T) {
+		cp := devom.NewCSVDevotionalParser(*devom.NewAPI(srv.URL), mapping)
+		d := feed.NewDestination("plan", "publisher", "author")
+		d.FieldMap = "dia=Dia"
+		cp.Destination(d)
+
+		_, err := cp.Parse(context.Background(), strings.NewReader(csvDevotionals))
+
+		assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+	})
+}
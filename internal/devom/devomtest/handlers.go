@@ -0,0 +1,204 @@
+package devomtest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/gorilla/mux"
+)
+
+type addDailyDevotionalReq struct {
+	DevotionalId string `json:"devotionalId"`
+	Day          int    `json:"day"`
+}
+
+type addDevotionalTopicReq struct {
+	TopicId string `json:"topicId"`
+}
+
+func (s *Server) createDevotional(w http.ResponseWriter, r *http.Request) {
+	var dev devom.Devotional
+	if err := json.NewDecoder(r.Body).Decode(&dev); err != nil || dev.Id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.devotionals[dev.Id]; ok {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	s.devotionals[dev.Id] = dev
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) updateDevotional(w http.ResponseWriter, r *http.Request) {
+	var dev devom.Devotional
+	if err := json.NewDecoder(r.Body).Decode(&dev); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	dev.Id = mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.devotionals[dev.Id]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	s.devotionals[dev.Id] = dev
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getDevotionals(w http.ResponseWriter, r *http.Request) {
+	authorId := r.URL.Query().Get("authorId")
+
+	devs := []devom.Devotional{}
+	for _, dev := range s.Devotionals() {
+		if authorId == "" || dev.AuthorId == authorId {
+			devs = append(devs, dev)
+		}
+	}
+	writeJSON(w, devs)
+}
+
+func (s *Server) addDevotionalTopic(w http.ResponseWriter, r *http.Request) {
+	var req addDevotionalTopicReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dev, ok := s.devotionals[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	dev.Topics = append(dev.Topics, req.TopicId)
+	s.devotionals[id] = dev
+	s.devotionalTopics[id] = append(s.devotionalTopics[id], req.TopicId)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) createPlan(w http.ResponseWriter, r *http.Request) {
+	var plan devom.Plan
+	if err := json.NewDecoder(r.Body).Decode(&plan); err != nil || plan.Id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.plans[plan.Id]; ok {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	s.plans[plan.Id] = plan
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) getPlans(w http.ResponseWriter, r *http.Request) {
+	authorId := r.URL.Query().Get("authorId")
+
+	plans := []devom.Plan{}
+	for _, plan := range s.Plans() {
+		if authorId == "" || plan.AuthorId == authorId {
+			plans = append(plans, plan)
+		}
+	}
+	writeJSON(w, plans)
+}
+
+func (s *Server) getPlan(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	plan, ok := s.plans[mux.Vars(r)["id"]]
+	s.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(w, plan)
+}
+
+func (s *Server) addDailyDevotional(w http.ResponseWriter, r *http.Request) {
+	var req addDailyDevotionalReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	planId := mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, planOk := s.plans[planId]
+	_, devOk := s.devotionals[req.DevotionalId]
+	if !planOk || !devOk {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	//without day, the devotional is the next one
+	if req.Day == 0 {
+		req.Day = len(s.dailyDevotionals[planId]) + 1
+	}
+	for _, dd := range s.dailyDevotionals[planId] {
+		if dd.Day == req.Day {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+	s.dailyDevotionals[planId] = append(s.dailyDevotionals[planId], dailyDevotional{req.Day, req.DevotionalId})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) getDailyDevotionals(w http.ResponseWriter, r *http.Request) {
+	planId := mux.Vars(r)["id"]
+
+	s.mu.Lock()
+	_, ok := s.plans[planId]
+	dds := []devom.DailyDevotional{}
+	for _, dd := range s.dailyDevotionals[planId] {
+		dds = append(dds, devom.DailyDevotional{Day: dd.Day, Devotional: s.devotionals[dd.DevotionalId]})
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeJSON(w, dds)
+}
+
+func (s *Server) createTopic(w http.ResponseWriter, r *http.Request) {
+	var topic devom.Topic
+	if err := json.NewDecoder(r.Body).Decode(&topic); err != nil || topic.Id == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.topics {
+		if t.Id == topic.Id || t.Title == topic.Title {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+	s.topics = append(s.topics, topic)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) getTopics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.Topics())
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
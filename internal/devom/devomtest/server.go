@@ -0,0 +1,203 @@
+// Package devomtest provides an in-memory devom API server for tests
+package devomtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/gorilla/mux"
+)
+
+// Request is a request received by the Server
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Body   string
+}
+
+type dailyDevotional struct {
+	Day          int    `json:"day"`
+	DevotionalId string `json:"devotionalId"`
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	devotionals      map[string]devom.Devotional
+	plans            map[string]devom.Plan
+	dailyDevotionals map[string][]dailyDevotional
+	topics           []devom.Topic
+	devotionalTopics map[string][]string
+	requests         []Request
+}
+
+// NewServer starts an empty devom API, the caller must Close it
+func NewServer() *Server {
+	s := &Server{
+		devotionals:      make(map[string]devom.Devotional),
+		plans:            make(map[string]devom.Plan),
+		dailyDevotionals: make(map[string][]dailyDevotional),
+		devotionalTopics: make(map[string][]string),
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/devotionals", s.createDevotional).Methods(http.MethodPost)
+	router.HandleFunc("/devotionals", s.getDevotionals).Methods(http.MethodGet)
+	router.HandleFunc("/devotionals/{id}", s.updateDevotional).Methods(http.MethodPut)
+	router.HandleFunc("/devotionals/{id}/topics/add", s.addDevotionalTopic).Methods(http.MethodPost)
+	router.HandleFunc("/yearly-plans", s.createPlan).Methods(http.MethodPost)
+	router.HandleFunc("/yearly-plans", s.getPlans).Methods(http.MethodGet)
+	router.HandleFunc("/yearly-plans/{id}", s.getPlan).Methods(http.MethodGet)
+	router.HandleFunc("/yearly-plans/{id}/devotionals", s.addDailyDevotional).Methods(http.MethodPost)
+	router.HandleFunc("/yearly-plans/{id}/devotionals", s.getDailyDevotionals).Methods(http.MethodGet)
+	router.HandleFunc("/categories", s.createTopic).Methods(http.MethodPost)
+	router.HandleFunc("/categories", s.getTopics).Methods(http.MethodGet)
+
+	s.Server = httptest.NewServer(s.record(router))
+	return s
+}
+
+// SeedDevotional stores a devotional
+func (s *Server) SeedDevotional(dev devom.Devotional) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devotionals[dev.Id] = dev
+}
+
+// SeedPlan stores a plan
+func (s *Server) SeedPlan(plan devom.Plan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plans[plan.Id] = plan
+}
+
+// SeedDailyDevotional adds a stored devotional to a plan day
+func (s *Server) SeedDailyDevotional(planId, devotionalId string, day int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dailyDevotionals[planId] = append(s.dailyDevotionals[planId], dailyDevotional{day, devotionalId})
+}
+
+// SeedTopic stores a topic
+func (s *Server) SeedTopic(topic devom.Topic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.topics = append(s.topics, topic)
+}
+
+// SeedYearlyPlan stores the year topic and its plan with a devotional for each day,
+// the way devom organizes the yearly devotionals
+func (s *Server) SeedYearlyPlan(year int, authorId string, days int) devom.Plan {
+	title := fmt.Sprint(year)
+	topic := devom.Topic{Id: fmt.Sprintf("topic-%d", year), Title: title}
+	plan := devom.Plan{Id: fmt.Sprintf("plan-%d", year), Title: title, TopicId: topic.Id, AuthorId: authorId}
+
+	s.SeedTopic(topic)
+	s.SeedPlan(plan)
+	for day := 1; day <= days; day++ {
+		dev := devom.Devotional{
+			Id:       fmt.Sprintf("devotional-%d-%d", year, day),
+			Title:    fmt.Sprintf("%d %d", year, day),
+			AuthorId: authorId,
+		}
+		s.SeedDevotional(dev)
+		s.SeedDailyDevotional(plan.Id, dev.Id, day)
+	}
+	return plan
+}
+
+// Requests returns the received requests in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// MutatingRequests returns the received requests changing the devom state
+func (s *Server) MutatingRequests() []Request {
+	var reqs []Request
+	for _, req := range s.Requests() {
+		if req.Method != http.MethodGet {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs
+}
+
+// Devotional returns a stored devotional
+func (s *Server) Devotional(id string) (devom.Devotional, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dev, ok := s.devotionals[id]
+	return dev, ok
+}
+
+// Devotionals returns the stored devotionals sorted by title
+func (s *Server) Devotionals() []devom.Devotional {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	devs := make([]devom.Devotional, 0, len(s.devotionals))
+	for _, dev := range s.devotionals {
+		devs = append(devs, dev)
+	}
+	sort.Slice(devs, func(i, j int) bool { return devs[i].Title < devs[j].Title })
+	return devs
+}
+
+// Plans returns the stored plans sorted by title
+func (s *Server) Plans() []devom.Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plans := make([]devom.Plan, 0, len(s.plans))
+	for _, plan := range s.plans {
+		plans = append(plans, plan)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].Title < plans[j].Title })
+	return plans
+}
+
+// Topics returns the stored topics in creation order
+func (s *Server) Topics() []devom.Topic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]devom.Topic(nil), s.topics...)
+}
+
+// DailyDevotionals returns the devotional id of each plan day
+func (s *Server) DailyDevotionals(planId string) map[int]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	days := make(map[int]string)
+	for _, dd := range s.dailyDevotionals[planId] {
+		days[dd.Day] = dd.DevotionalId
+	}
+	return days
+}
+
+// DevotionalTopics returns the topic ids added to a devotional
+func (s *Server) DevotionalTopics(devotionalId string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.devotionalTopics[devotionalId]...)
+}
+
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{r.Method, r.URL.Path, r.URL.RawQuery, string(body)})
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
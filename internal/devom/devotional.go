@@ -12,6 +12,15 @@ type Devotional struct {
 	Topics       []string `json:"topics"`
 }
 
+func (d Devotional) hasTopic(topicId string) bool {
+	for _, id := range d.Topics {
+		if id == topicId {
+			return true
+		}
+	}
+	return false
+}
+
 type Passage struct {
 	Text      string `json:"text"`
 	Reference string `json:"reference"`
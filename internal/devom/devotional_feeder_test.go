@@ -2,11 +2,11 @@ package devom_test
 
 import (
 	"context"
-	"log"
 	"os"
 	"testing"
 
 	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
 	feed "github.com/amelendres/go-feeder/pkg"
 	"github.com/amelendres/go-feeder/pkg/cloud"
 	"github.com/amelendres/go-feeder/pkg/fs"
@@ -16,9 +16,7 @@ import (
 )
 
 var (
-	devomAPIUrl = "localhost:8030"
-	api         = *devom.NewAPI(devomAPIUrl)
-	path        = map[string]string{
+	path = map[string]string{
 		"dev-ok":              "./_test_devotionals-ok.docx",
 		"dev-ko":              "./_test_devotionals-ko.docx",
 		"no-file":             "./_test_not-exist-file.docx",
@@ -30,13 +28,15 @@ var (
 )
 
 func TestDevotionalFeeder_FS(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
 
-	dp := devom.NewDevotionalParser(api)
+	dp := devom.NewDevotionalParser(*devom.NewAPI(srv.URL))
 	fp := &fs.FileProvider{}
 	df := feed.NewFeeder(dp, []feed.FileProvider{fp})
 
 	t.Run("it reads Feeds with UnknownFeeds", func(t *testing.T) {
-		feeds, err := df.Feeds(path["dev-ko"])
+		feeds, err := df.Feeds(context.Background(), path["dev-ko"])
 
 		assert.Nil(t, err)
 		assert.Equal(t, 4, len(feeds.Items))
@@ -44,14 +44,14 @@ func TestDevotionalFeeder_FS(t *testing.T) {
 	})
 
 	t.Run("it fails read feeds without resource file", func(t *testing.T) {
-		feeds, err := df.Feeds(path["no-file"])
+		feeds, err := df.Feeds(context.Background(), path["no-file"])
 
 		assert.NotNil(t, err)
 		assert.Nil(t, feeds)
 	})
 
 	t.Run("it reads valid Feeds", func(t *testing.T) {
-		feeds, err := df.Feeds(path["dev-ok"])
+		feeds, err := df.Feeds(context.Background(), path["dev-ok"])
 
 		assert.Empty(t, err)
 		assert.Empty(t, feeds.UnknownItems)
@@ -62,18 +62,21 @@ func TestDevotionalFeeder_FS(t *testing.T) {
 func TestDevotionalFeeder_GD(t *testing.T) {
 	googleAPIKey := os.Getenv("GOOGLE_API_KEY")
 	if googleAPIKey == "" {
-		log.Fatal("ERROR: you must provide a Google Api Key")
+		t.Skip("you must provide a Google Api Key")
 	}
 
 	ctx := context.Background()
 	driveService, _ := drive.NewService(ctx, option.WithAPIKey(googleAPIKey))
 
+	srv := devomtest.NewServer()
+	defer srv.Close()
+
 	fp := cloud.NewGDFileProvider(driveService)
-	dp := devom.NewDevotionalParser(api)
+	dp := devom.NewDevotionalParser(*devom.NewAPI(srv.URL))
 	df := feed.NewFeeder(dp, []feed.FileProvider{fp})
 
 	t.Run("it reads from Google Drive", func(t *testing.T) {
-		feeds, err := df.Feeds(path["drive-dev-2019a"])
+		feeds, err := df.Feeds(context.Background(), path["drive-dev-2019a"])
 
 		assert.Nil(t, err)
 		assert.Equal(t, 100, len(feeds.Items))
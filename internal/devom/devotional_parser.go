@@ -1,6 +1,7 @@
 package devom
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,17 +9,17 @@ import (
 	"strconv"
 	"strings"
 
-	"code.sajari.com/docconv"
 	feed "github.com/amelendres/go-feeder/pkg"
 )
 
 var (
 	ErrUndefinedDestination        = errors.New("Undefined destination")
+	ErrFeedDoesNotHaveTitle        = errors.New("Feed does not have title")
 	ErrFeedDoesNotHavePassage      = errors.New("Feed does not have passage")
 	ErrFeedDoesNotHaveContent      = errors.New("Feed does not have content")
 	ErrFeedDoesNotHaveValidPassage = errors.New("Feed does not have a valid passage")
 	ErrReadingResource             = func(err error) error {
-		return fmt.Errorf("Error reading document: %w", err)
+		return feed.NewError(feed.ErrCodeUnreadableFile, fmt.Errorf("Error reading document: %w", err))
 	}
 	ErrDoesNotHaveValidDay = func(want, got int) error {
 		return fmt.Errorf("Invalid day: want %d, but got %d", want, got)
@@ -33,39 +34,88 @@ type devotionalParser struct {
 	to          *feed.Destination
 	items       map[string]*feed.Item
 	devotionals map[string]*Devotional
+	// richTexts are the formats written, only the documents keep their emphasis, lists and quotes
+	richTexts map[feed.Format]richText
 }
 
 func NewDevotionalParser(api API) feed.Parser {
-	return &devotionalParser{api: api}
+	return &devotionalParser{api: api, richTexts: richTexts}
 }
 
 func (dp *devotionalParser) Destination(d *feed.Destination) {
 	dp.to = d
 }
 
-func (dp *devotionalParser) Parse(r io.Reader) (*feed.ParsedItems, error) {
-	feeds := []feed.Item{}
-	unknownFeeds := []feed.UnknownItem{}
+func (dp *devotionalParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	rt, rich := dp.richText()
+	if rich {
+		ps, err := readParagraphs(r)
+		if err != nil {
+			return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{}, Items: []feed.Item{}}, err
+		}
+		return dp.parse(ctx, richRecords(splitParagraphs(ps), rt))
+	}
 
 	txt, err := dp.read(r)
 	if err != nil {
-		return &feed.ParsedItems{unknownFeeds, feeds}, err
+		return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{}, Items: []feed.Item{}}, err
+	}
+
+	return dp.parse(ctx, textRecords(splitDevotionals(txt), parseDevotional))
+}
+
+// richText writes the content and the passage in the destination format, if it is not plain text
+func (dp *devotionalParser) richText() (richText, bool) {
+	if dp.to == nil {
+		return nil, false
+	}
+	rt, ok := dp.richTexts[dp.to.Format]
+	return rt, ok
+}
+
+// Writes tells whether the parser writes the format, the plain text is always written
+func (dp *devotionalParser) Writes(f feed.Format) bool {
+	_, ok := dp.richTexts[f]
+	return ok || f == feed.FormatText
+}
+
+// record is a devotional of a document, with its raw lines to report it as unknown
+type record struct {
+	lines []string
+	item  func() (feed.Item, error)
+}
+
+// textRecords parses each devotional text with parseDev
+func textRecords(devs []string, parseDev func(string) (feed.Item, error)) []record {
+	records := make([]record, 0, len(devs))
+	for _, dev := range devs {
+		dev := dev
+		records = append(records, record{lines(dev), func() (feed.Item, error) { return parseDev(dev) }})
 	}
+	return records
+}
+
+// parse validates the devotionals of the records have sequential days and unique titles
+func (dp *devotionalParser) parse(ctx context.Context, devs []record) (*feed.ParsedItems, error) {
+	feeds := []feed.Item{}
+	unknownFeeds := []feed.UnknownItem{}
 
-	_ = dp.refreshCache()
+	_ = dp.refreshCache(ctx)
 
 	dp.items = make(map[string]*feed.Item)
-	devs := splitDevotionals(txt)
 	lastDay := 0
 	for _, dev := range devs {
-		f, err := parseDevotional(dev)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f, err := dev.item()
 		if err != nil {
-			unknownFeeds = append(unknownFeeds, feed.UnknownItem{lines(dev), err.Error()})
+			unknownFeeds = append(unknownFeeds, feed.UnknownItem{Item: dev.lines, ItemError: err.Error()})
 			continue
 		}
 		day, err := strconv.Atoi(f["day"])
 		if err != nil {
-			unknownFeeds = append(unknownFeeds, feed.UnknownItem{lines(dev), err.Error()})
+			unknownFeeds = append(unknownFeeds, feed.UnknownItem{Item: dev.lines, ItemError: err.Error()})
 			continue
 		}
 
@@ -77,14 +127,14 @@ func (dp *devotionalParser) Parse(r io.Reader) (*feed.ParsedItems, error) {
 			}
 			if day != lastDay+1 {
 				err = ErrDoesNotHaveValidDay(lastDay+1, day)
-				unknownFeeds = append(unknownFeeds, feed.UnknownItem{lines(dev), err.Error()})
+				unknownFeeds = append(unknownFeeds, feed.UnknownItem{Item: dev.lines, ItemError: err.Error()})
 				continue
 			}
 		}
 
 		//validate title
 		if err = dp.uniqueTitle(f["title"]); err != nil {
-			unknownFeeds = append(unknownFeeds, feed.UnknownItem{lines(dev), err.Error()})
+			unknownFeeds = append(unknownFeeds, feed.UnknownItem{Item: dev.lines, ItemError: err.Error()})
 			continue
 		}
 
@@ -92,7 +142,7 @@ func (dp *devotionalParser) Parse(r io.Reader) (*feed.ParsedItems, error) {
 		dp.items[f["title"]] = &f
 	}
 
-	return &feed.ParsedItems{unknownFeeds, feeds}, nil
+	return &feed.ParsedItems{UnknownItems: unknownFeeds, Items: feeds}, nil
 }
 
 func (dp *devotionalParser) uniqueTitle(title string) error {
@@ -101,6 +151,9 @@ func (dp *devotionalParser) uniqueTitle(title string) error {
 	if ok {
 		return ErrTitleAlreadyExists(title)
 	}
+	if dp.to != nil && dp.to.Upsert {
+		return nil
+	}
 	_, ok = dp.devotionals[title]
 	if ok {
 		return ErrTitleAlreadyExists(title)
@@ -109,24 +162,17 @@ func (dp *devotionalParser) uniqueTitle(title string) error {
 }
 
 func (dp *devotionalParser) read(r io.Reader) (string, error) {
-
-	content, _, err := docconv.ConvertDoc(r)
-
-	if err != nil {
-		return "", ErrReadingResource(err)
-	}
-
-	return content, nil
+	return readDocument(r)
 }
 
-func (dp *devotionalParser) refreshCache() error {
+func (dp *devotionalParser) refreshCache(ctx context.Context) error {
 	dp.devotionals = make(map[string]*Devotional)
 
 	if dp.to == nil {
 		return nil
 	}
 
-	devotionals, err := dp.api.getDevotionals(dp.to.AuthorId)
+	devotionals, err := dp.api.getDevotionals(ctx, dp.to.AuthorId)
 	if err != nil {
 		return err
 	}
@@ -137,68 +183,80 @@ func (dp *devotionalParser) refreshCache() error {
 	return nil
 }
 
-func splitDevotionals(text string) []string {
+// dayLine starts each devotional of a document with its day alone in a line
+var dayLine = regexp.MustCompile(`(^|\n)([0-9]+)(\n|\s*\n)`)
 
-	day := regexp.MustCompile(`\n([0-9]+)(\n|\s*\n)`)
+// splitDevotionals splits the devotionals by their day line, as "<day>\n<text>",
+// skipping the heading of the document before the first one
+func splitDevotionals(text string) []string {
+	days := dayLine.FindAllStringSubmatchIndex(text, -1)
 
-	devTexts := day.Split(text, -1)
-	devTexts = trimSlice(devTexts)
-	days := day.FindAllString(text, -1)
-	// fmt.Printf("text: %+v\n total: %d\n", text, len(devTexts))
-	// fmt.Printf("days: %+v\n total: %d\n", days, len(days))
 	var devs []string
-	for i, item := range devTexts {
-		devs = append(devs, strings.TrimSpace(days[i])+"\n"+item)
+	for i, d := range days {
+		end := len(text)
+		if i+1 < len(days) {
+			end = days[i+1][0]
+		}
+		devs = append(devs, text[d[4]:d[5]]+"\n"+text[d[1]:end])
 	}
-
 	return devs
 }
 
+const titleIdx = 1
+
+// sections are the line indexes of the passage and the content of a devotional
+type sections struct {
+	passageStart, passageEnd int
+	contentStart             int
+}
+
 func parseDevotional(text string) (feed.Item, error) {
-	titleIdx := 1
 	lines := lines(text)
+	dev, s, err := devotionalSections(lines)
+	if err != nil {
+		return nil, err
+	}
 
-	dev := make(map[string]string)
-	dev["day"] = lines[0]
-	dev["title"] = lines[titleIdx]
+	passage, err := passage(lines, s.passageStart, s.passageEnd)
+	if err != nil {
+		return nil, err
+	}
+	dev["passage_text"], dev["passage_reference"] = passage.Text, passage.Reference
+	dev["content"] = content(lines, s.contentStart, len(lines)-1)
+
+	return dev, nil
+}
 
+// devotionalSections reads the day, title and bible reading of the devotional lines, and finds their sections
+func devotionalSections(lines []string) (feed.Item, sections, error) {
+	var s sections
 	if len(lines) < 4 {
-		return nil, feed.ErrUnknownFeed
+		return nil, s, feed.ErrUnknownFeed
 	}
 
+	dev := make(map[string]string)
+	dev["day"] = lines[0]
+	dev["title"] = lines[titleIdx]
+
 	var bibleReadingIdx int
 	dev["bible_reading"], bibleReadingIdx = bibleReading(lines)
 
 	if bibleReadingIdx == titleIdx+1 {
-		return nil, ErrFeedDoesNotHavePassage
+		return nil, s, ErrFeedDoesNotHavePassage
 	}
 
 	if bibleReadingIdx > titleIdx+1 {
-		passage, err := passage(lines, titleIdx+1, bibleReadingIdx-1)
-		if err != nil {
-			return nil, err
-		}
-		dev["passage_text"], dev["passage_reference"] = passage.Text, passage.Reference
-		dev["content"] = content(lines, bibleReadingIdx+1, len(lines)-1)
-
-	} else {
-		contentIdx := contentIndex(lines)
-		if contentIdx < 0 {
-			return nil, ErrFeedDoesNotHaveContent
-		}
-
-		if contentIdx == titleIdx+1 {
-			return nil, ErrFeedDoesNotHavePassage
-		}
-		passage, err := passage(lines, titleIdx+1, contentIdx-1)
-		if err != nil {
-			return nil, err
-		}
-		dev["passage_text"], dev["passage_reference"] = passage.Text, passage.Reference
-		dev["content"] = content(lines, contentIdx, len(lines)-1)
+		return dev, sections{titleIdx + 1, bibleReadingIdx - 1, bibleReadingIdx + 1}, nil
 	}
 
-	return dev, nil
+	contentIdx := contentIndex(lines)
+	if contentIdx < 0 {
+		return nil, s, ErrFeedDoesNotHaveContent
+	}
+	if contentIdx == titleIdx+1 {
+		return nil, s, ErrFeedDoesNotHavePassage
+	}
+	return dev, sections{titleIdx + 1, contentIdx - 1, contentIdx}, nil
 }
 
 func lines(txt string) []string {
@@ -268,9 +326,11 @@ func isPassage(txt string) bool {
 	return match
 }
 
+// lastPassageChar closes the passage text before its reference
+var lastPassageChar = regexp.MustCompile(`(”|")(\s*)\(`)
+
 func splitPassage(txt string) (text string, reference string, err error) {
 	var passage []string
-	lastPassageChar := regexp.MustCompile(`(”|")(\s*)\(`)
 	occurrences := lastPassageChar.FindAllString(txt, -1)
 
 	if len(occurrences) == 0 {
@@ -0,0 +1,26 @@
+package devom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitDevotionals(t *testing.T) {
+
+	t.Run("it skips the heading of the document", func(t *testing.T) {
+		devs := splitDevotionals("Devocionales 2021\n001\nEL CAMINO\ncontent 1\n002\nLA LUZ\ncontent 2\n")
+
+		assert.Equal(t, []string{"001\nEL CAMINO\ncontent 1", "002\nLA LUZ\ncontent 2\n"}, devs)
+	})
+
+	t.Run("it keeps the first day of a document without heading", func(t *testing.T) {
+		devs := splitDevotionals("001\nEL CAMINO\ncontent 1\n002\nLA LUZ\ncontent 2")
+
+		assert.Equal(t, []string{"001\nEL CAMINO\ncontent 1", "002\nLA LUZ\ncontent 2"}, devs)
+	})
+
+	t.Run("it does not split a document without days", func(t *testing.T) {
+		assert.Empty(t, splitDevotionals("Devocionales 2021\nEL CAMINO\n"))
+	})
+}
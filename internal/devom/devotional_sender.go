@@ -1,20 +1,29 @@
 package devom
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"strconv"
+	"strings"
+	"unicode"
 
 	feed "github.com/amelendres/go-feeder/pkg"
 	"github.com/google/uuid"
 )
 
+const (
+	kindDevotional      = "devotional"
+	kindDailyDevotional = "daily_devotional"
+)
+
 var (
-	ErrAddingDailyDevotional = func(want, got int) error {
-		return fmt.Errorf("fails adding daily devotional, unexpected response status, want %d but got %d", want, got)
+	ErrImportingDevotionals = func(code feed.ErrorCode) error {
+		return feed.NewError(code, errors.New("fails importing devotionals"))
 	}
-	ErrCreatingDevotional = func(want, got int) error {
-		return fmt.Errorf("fails creating devotional, unexpected response status, want %d but got %d", want, got)
+
+	ErrLoadingDestination = func(err error) error {
+		return fmt.Errorf("fails loading the destination plans and devotionals: %w", err)
 	}
 )
 
@@ -33,35 +42,115 @@ func (ps *devotionalSender) Destination(d *feed.Destination) {
 	ps.to = d
 }
 
-func (ps *devotionalSender) Send(feeds []feed.Item) error {
-	if err := ps.refreshCache(); err != nil {
-		log.Fatal(err)
-		return nil
+func (ps *devotionalSender) Send(ctx context.Context, feeds []feed.Item) (*feed.Report, error) {
+	return ps.send(ctx, feeds, false)
+}
+
+func (ps *devotionalSender) Plan(ctx context.Context, feeds []feed.Item) (*feed.Report, error) {
+	return ps.send(ctx, feeds, true)
+}
+
+// send adds the feeds to the destination plan, on dry-run it only reports the changes
+func (ps *devotionalSender) send(ctx context.Context, feeds []feed.Item, dryRun bool) (*feed.Report, error) {
+	if err := ps.refreshCache(ctx); err != nil {
+		return nil, ErrLoadingDestination(err)
 	}
 
-	var err error
+	report := feed.NewReport()
+	var errs []error
 	for _, f := range feeds {
-		dev := ps.mapItem(f)
-		day, _ := strconv.Atoi(f["day"])
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		changes, outcome, err := ps.sendItem(ctx, f, dryRun)
+		report.Add(f, outcome, changes, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 || dryRun {
+		return report, nil
+	}
+	return report, ErrImportingDevotionals(failureCode(errs))
+}
 
-		//adding current Devotional as Daily Devotional
-		if currentDev := ps.devotional(dev.Title); currentDev != nil {
-			if dd := ps.dailyDevotional(currentDev.Id); dd != nil {
-				continue
-			}
-			_ = ps.api.addDailyDevotional(AddDailyDevotionalReq{ps.to.PlanId, currentDev.Id, day})
-			continue
+// failureCode classifies the failed items, unavailable when any of them is so they are retried,
+// otherwise the code of the first classified one or rejected
+func failureCode(errs []error) feed.ErrorCode {
+	code := feed.ErrCodeRejected
+	classified := false
+	for _, err := range errs {
+		switch c := feed.Code(err); {
+		case c == feed.ErrCodeUnavailable:
+			return c
+		case c != feed.ErrCodeInternal && !classified:
+			code, classified = c, true
 		}
+	}
+	return code
+}
+
+// sendItem adds a feed to the destination plan and returns the changes and the outcome
+func (ps *devotionalSender) sendItem(ctx context.Context, f feed.Item, dryRun bool) ([]feed.Change, feed.Outcome, error) {
+	dev := ps.mapItem(f)
+	day, _ := strconv.Atoi(f["day"])
 
-		if err = ps.api.createDevotional(dev); err != nil {
-			return err
+	currentDev := ps.devotional(dev.Title)
+	if currentDev == nil && ps.to.Upsert {
+		if dd := ps.dayDevotional(day); dd != nil {
+			currentDev = &dd.Devotional
 		}
-		err = ps.api.addDailyDevotional(AddDailyDevotionalReq{ps.to.PlanId, dev.Id, day})
-		if err != nil {
-			return err
+	}
+
+	if currentDev == nil {
+		changes := []feed.Change{
+			{Action: feed.ActionCreate, Kind: kindDevotional, Title: dev.Title, Day: day},
+			{Action: feed.ActionAttach, Kind: kindDailyDevotional, Title: dev.Title, Day: day},
+		}
+		if dryRun {
+			return changes, feed.OutcomeCreated, nil
+		}
+		if err := ps.api.createDevotional(ctx, dev); err != nil {
+			return changes, feed.OutcomeFailed, err
 		}
+		err := ps.api.addDailyDevotional(ctx, AddDailyDevotionalReq{ps.to.PlanId, dev.Id, day})
+		return changes, feed.OutcomeCreated, err
 	}
-	return err
+
+	var changes []feed.Change
+	outcome := feed.OutcomeSkipped
+	if ps.to.Upsert {
+		//updating current Devotional
+		if sameContent(*currentDev, dev) {
+			outcome = feed.OutcomeUnchanged
+			changes = append(changes, feed.Change{Action: feed.ActionUnchanged, Kind: kindDevotional, Title: dev.Title, Day: day})
+		} else {
+			outcome = feed.OutcomeUpdated
+			changes = append(changes, feed.Change{Action: feed.ActionUpdate, Kind: kindDevotional, Title: dev.Title, Day: day})
+			if !dryRun {
+				if err := ps.api.updateDevotional(ctx, updatedDevotional(*currentDev, dev)); err != nil {
+					return changes, feed.OutcomeFailed, err
+				}
+			}
+		}
+	}
+
+	//adding current Devotional as Daily Devotional
+	if dd := ps.dailyDevotional(currentDev.Id); dd != nil {
+		if !ps.to.Upsert {
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindDailyDevotional, Title: dev.Title, Day: day})
+		}
+		return changes, outcome, nil
+	}
+	changes = append(changes, feed.Change{Action: feed.ActionAttach, Kind: kindDailyDevotional, Title: dev.Title, Day: day})
+	if outcome == feed.OutcomeSkipped {
+		outcome = feed.OutcomeAttached
+	}
+	if dryRun {
+		return changes, outcome, nil
+	}
+	err := ps.api.addDailyDevotional(ctx, AddDailyDevotionalReq{ps.to.PlanId, currentDev.Id, day})
+	return changes, outcome, err
 }
 
 func (ps *devotionalSender) mapItem(feed feed.Item) Devotional {
@@ -79,14 +168,14 @@ func (ps *devotionalSender) mapItem(feed feed.Item) Devotional {
 	}
 }
 
-func (ps *devotionalSender) refreshCache() error {
-	plan, err := ps.api.getPlan(ps.to.PlanId)
+func (ps *devotionalSender) refreshCache(ctx context.Context) error {
+	plan, err := ps.api.getPlan(ctx, ps.to.PlanId)
 	if err != nil {
 		return err
 	}
 	ps.plan = plan
 
-	devotionals, err := ps.api.getDevotionals(ps.to.AuthorId)
+	devotionals, err := ps.api.getDevotionals(ctx, ps.to.AuthorId)
 	if err != nil {
 		return err
 	}
@@ -106,6 +195,16 @@ func (ps *devotionalSender) dailyDevotional(id string) *DailyDevotional {
 	return nil
 }
 
+func (ps *devotionalSender) dayDevotional(day int) *DailyDevotional {
+	//from cache
+	for _, dd := range ps.plan.DailyDevotionals {
+		if dd.Day == day {
+			return dd
+		}
+	}
+	return nil
+}
+
 func (ps *devotionalSender) devotional(title string) *Devotional {
 	//from cache
 	if dev, ok := ps.devotionals[title]; ok {
@@ -113,3 +212,26 @@ func (ps *devotionalSender) devotional(title string) *Devotional {
 	}
 	return nil
 }
+
+// sameContent reports whether the devotional fed from the document matches the current one,
+// the parsers end the content with blank lines devom may not keep
+func sameContent(current, dev Devotional) bool {
+	return sameText(current.Title, dev.Title) &&
+		sameText(current.Passage.Text, dev.Passage.Text) &&
+		sameText(current.Passage.Reference, dev.Passage.Reference) &&
+		sameText(current.Content, dev.Content) &&
+		sameText(current.BibleReading, dev.BibleReading)
+}
+
+func sameText(a, b string) bool {
+	return strings.TrimRightFunc(a, unicode.IsSpace) == strings.TrimRightFunc(b, unicode.IsSpace)
+}
+
+// updatedDevotional returns the current devotional with the content fed from the document
+func updatedDevotional(current, dev Devotional) Devotional {
+	current.Title = dev.Title
+	current.Passage = dev.Passage
+	current.Content = dev.Content
+	current.BibleReading = dev.BibleReading
+	return current
+}
@@ -0,0 +1,259 @@
+package devom_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	authorId    = "eeef78ed-043e-40db-9eae-8a0d77950ceb"
+	publisherId = "e5f12936-1339-4dbc-b339-fb0ba42b13a9"
+)
+
+func newDevotionalItem(day, title, content string) feed.Item {
+	return feed.Item{
+		"day":               day,
+		"title":             title,
+		"passage_text":      "“Lámpara es a mis pies tu palabra”",
+		"passage_reference": "(Salmos 119:105)",
+		"bible_reading":     "Lectura: Salmos 119",
+		"content":           content,
+	}
+}
+
+func newDevotionalSender(srv *devomtest.Server, planId string, upsert bool) feed.Sender {
+	sender := devom.NewDevotionalSender(*devom.NewAPI(srv.URL))
+	dest := feed.NewDestination(planId, publisherId, authorId)
+	dest.Upsert = upsert
+	sender.Destination(dest)
+	return sender
+}
+
+func TestDevotionalSender_Send(t *testing.T) {
+
+	t.Run("it creates the devotionals as plan days", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		sender := newDevotionalSender(srv, "plan-2021", false)
+
+		report, err := sender.Send(context.Background(), []feed.Item{
+			newDevotionalItem("1", "La palabra", "content 1"),
+			newDevotionalItem("2", "La luz", "content 2"),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, report.Count(feed.OutcomeCreated))
+		assert.Equal(t, 2, len(srv.Devotionals()))
+		days := srv.DailyDevotionals("plan-2021")
+		dev, _ := srv.Devotional(days[2])
+		assert.Equal(t, "La luz", dev.Title)
+		assert.Equal(t, "content 2", dev.Content)
+	})
+
+	t.Run("it attaches an existing devotional and skips the plan days", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		srv.SeedDevotional(devom.Devotional{Id: "dev-1", Title: "La palabra", AuthorId: authorId})
+		srv.SeedDevotional(devom.Devotional{Id: "dev-2", Title: "La luz", AuthorId: authorId})
+		srv.SeedDailyDevotional("plan-2021", "dev-2", 2)
+		sender := newDevotionalSender(srv, "plan-2021", false)
+
+		report, err := sender.Send(context.Background(), []feed.Item{
+			newDevotionalItem("1", "La palabra", "content 1"),
+			newDevotionalItem("2", "La luz", "content 2"),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, feed.OutcomeAttached, report.Items[0].Outcome)
+		assert.Equal(t, []feed.Change{{Action: feed.ActionAttach, Kind: "daily_devotional", Title: "La palabra", Day: 1}}, report.Items[0].Changes)
+		assert.Equal(t, feed.OutcomeSkipped, report.Items[1].Outcome)
+		assert.Equal(t, []feed.Change{{Action: feed.ActionSkip, Kind: "daily_devotional", Title: "La luz", Day: 2}}, report.Items[1].Changes)
+		assert.Equal(t, 2, len(srv.Devotionals()))
+		assert.Equal(t, map[int]string{1: "dev-1", 2: "dev-2"}, srv.DailyDevotionals("plan-2021"))
+	})
+
+	t.Run("it updates the changed devotionals on upsert", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		unchanged := devom.Devotional{
+			Id:           "dev-1",
+			Title:        "La palabra",
+			Passage:      devom.NewPassage("“Lámpara es a mis pies tu palabra”", "(Salmos 119:105)"),
+			Content:      "content 1",
+			BibleReading: "Lectura: Salmos 119",
+			AuthorId:     authorId,
+		}
+		srv.SeedDevotional(unchanged)
+		srv.SeedDevotional(devom.Devotional{Id: "dev-2", Title: "La luz", Content: "old content", AuthorId: authorId})
+		srv.SeedDevotional(devom.Devotional{Id: "dev-3", Title: "Old title", AuthorId: authorId})
+		srv.SeedDailyDevotional("plan-2021", "dev-1", 1)
+		srv.SeedDailyDevotional("plan-2021", "dev-2", 2)
+		srv.SeedDailyDevotional("plan-2021", "dev-3", 3)
+		sender := newDevotionalSender(srv, "plan-2021", true)
+
+		report, err := sender.Send(context.Background(), []feed.Item{
+			newDevotionalItem("1", "La palabra", "content 1"),
+			newDevotionalItem("2", "La luz", "content 2"),
+			newDevotionalItem("3", "El camino", "content 3"),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []feed.Change{{Action: feed.ActionUnchanged, Kind: "devotional", Title: "La palabra", Day: 1}}, report.Items[0].Changes)
+		assert.Equal(t, feed.OutcomeUnchanged, report.Items[0].Outcome)
+		assert.Equal(t, []feed.Change{{Action: feed.ActionUpdate, Kind: "devotional", Title: "La luz", Day: 2}}, report.Items[1].Changes)
+		assert.Equal(t, feed.OutcomeUpdated, report.Items[1].Outcome)
+		assert.Equal(t, []feed.Change{{Action: feed.ActionUpdate, Kind: "devotional", Title: "El camino", Day: 3}}, report.Items[2].Changes)
+		assert.Equal(t, feed.OutcomeUpdated, report.Items[2].Outcome)
+		dev, _ := srv.Devotional("dev-2")
+		assert.Equal(t, "content 2", dev.Content)
+		dev, _ = srv.Devotional("dev-3")
+		assert.Equal(t, "El camino", dev.Title)
+		assert.Equal(t, 3, len(srv.Devotionals()))
+		assert.Equal(t, 2, len(srv.MutatingRequests()))
+	})
+
+	t.Run("it does not update a devotional differing in the trailing blank lines", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		srv.SeedDevotional(devom.Devotional{
+			Id:           "dev-1",
+			Title:        "La palabra",
+			Passage:      devom.NewPassage("“Lámpara es a mis pies tu palabra”", "(Salmos 119:105)"),
+			Content:      "first paragraph\n\nlast paragraph",
+			BibleReading: "Lectura: Salmos 119",
+			AuthorId:     authorId,
+		})
+		srv.SeedDailyDevotional("plan-2021", "dev-1", 1)
+		sender := newDevotionalSender(srv, "plan-2021", true)
+
+		report, err := sender.Send(context.Background(), []feed.Item{
+			newDevotionalItem("1", "La palabra", "first paragraph\n\nlast paragraph\n\n"),
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, feed.OutcomeUnchanged, report.Items[0].Outcome)
+		assert.Empty(t, srv.MutatingRequests())
+	})
+
+	t.Run("it updates a devotional differing only in the passage", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		srv.SeedDevotional(devom.Devotional{
+			Id:           "dev-1",
+			Title:        "La palabra",
+			Passage:      devom.NewPassage("“Lámpara es a mis pies tu palabra”", "(Salmos 119:106)"),
+			Content:      "content 1",
+			BibleReading: "Lectura: Salmos 119",
+			AuthorId:     authorId,
+		})
+		srv.SeedDailyDevotional("plan-2021", "dev-1", 1)
+		sender := newDevotionalSender(srv, "plan-2021", true)
+
+		report, err := sender.Send(context.Background(), []feed.Item{newDevotionalItem("1", "La palabra", "content 1")})
+
+		assert.Nil(t, err)
+		assert.Equal(t, feed.OutcomeUpdated, report.Items[0].Outcome)
+		dev, _ := srv.Devotional("dev-1")
+		assert.Equal(t, "(Salmos 119:105)", dev.Passage.Reference)
+	})
+}
+
+func TestDevotionalSender_Plan(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+	srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+	srv.SeedDevotional(devom.Devotional{Id: "dev-1", Title: "La palabra", AuthorId: authorId})
+	sender := newDevotionalSender(srv, "plan-2021", false)
+
+	report, err := sender.Plan(context.Background(), []feed.Item{
+		newDevotionalItem("1", "La palabra", "content 1"),
+		newDevotionalItem("2", "La luz", "content 2"),
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, feed.OutcomeAttached, report.Items[0].Outcome)
+	assert.Equal(t, []feed.Change{
+		{Action: feed.ActionAttach, Kind: "daily_devotional", Title: "La palabra", Day: 1},
+	}, report.Items[0].Changes)
+	assert.Equal(t, feed.OutcomeCreated, report.Items[1].Outcome)
+	assert.Equal(t, []feed.Change{
+		{Action: feed.ActionCreate, Kind: "devotional", Title: "La luz", Day: 2},
+		{Action: feed.ActionAttach, Kind: "daily_devotional", Title: "La luz", Day: 2},
+	}, report.Items[1].Changes)
+	assert.Empty(t, srv.MutatingRequests())
+}
+
+func TestDevotionalSender_Fails(t *testing.T) {
+
+	t.Run("it fails without the destination plan", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		sender := newDevotionalSender(srv, "does-not-exist", false)
+
+		_, err := sender.Send(context.Background(), []feed.Item{newDevotionalItem("1", "La palabra", "content 1")})
+
+		assert.Equal(t, feed.ErrCodeRejected, feed.Code(err))
+		assert.Empty(t, srv.MutatingRequests())
+	})
+
+	t.Run("it fails as the failed items, unavailable when any of them is", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		// devom rejects the devotionals titled "rechazo" and is unavailable for the "caída" ones
+		front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			switch {
+			case r.Method == http.MethodPost && strings.Contains(string(b), "rechazo"):
+				w.WriteHeader(http.StatusBadRequest)
+			case r.Method == http.MethodPost && strings.Contains(string(b), "caída"):
+				w.WriteHeader(http.StatusServiceUnavailable)
+			default:
+				r.Body = ioutil.NopCloser(bytes.NewReader(b))
+				srv.Config.Handler.ServeHTTP(w, r)
+			}
+		}))
+		defer front.Close()
+		sender := devom.NewDevotionalSender(*devom.NewAPI(front.URL, devom.WithRetry(0, 0)))
+		sender.Destination(feed.NewDestination("plan-2021", publisherId, authorId))
+
+		_, err := sender.Send(context.Background(), []feed.Item{newDevotionalItem("1", "rechazo", "content 1")})
+		assert.Equal(t, feed.ErrCodeRejected, feed.Code(err))
+
+		_, err = sender.Send(context.Background(), []feed.Item{
+			newDevotionalItem("1", "rechazo", "content 1"),
+			newDevotionalItem("2", "caída", "content 2"),
+		})
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+	})
+
+	t.Run("it stops when cancelled", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedPlan(devom.Plan{Id: "plan-2021", AuthorId: authorId})
+		sender := newDevotionalSender(srv, "plan-2021", false)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := sender.Send(ctx, []feed.Item{newDevotionalItem("1", "La palabra", "content 1")})
+
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Empty(t, srv.MutatingRequests())
+	})
+}
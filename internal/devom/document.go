@@ -0,0 +1,153 @@
+package devom
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"code.sajari.com/docconv"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"golang.org/x/net/html"
+)
+
+// odtMimeType is the content of the mimetype entry of an OpenDocument text
+const odtMimeType = "application/vnd.oasis.opendocument.text"
+
+var ErrUnsupportedDocument = feed.NewError(
+	feed.ErrCodeUnsupportedFile,
+	errors.New("unsupported document, want a .docx, .doc, .odt, .pdf, .rtf or .html"),
+)
+
+// converter extracts the text of a document
+type converter func(r io.Reader) (string, map[string]string, error)
+
+// readDocument extracts the text of a docx, doc, odt, pdf, rtf or html document
+func readDocument(r io.Reader) (string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", ErrReadingResource(err)
+	}
+	convert, err := detect(b)
+	if err != nil {
+		return "", err
+	}
+	content, _, err := convert(bytes.NewReader(b))
+	if err != nil {
+		return "", ErrReadingResource(err)
+	}
+	return content, nil
+}
+
+// detect chooses the converter of a document by its type
+func detect(b []byte) (converter, error) {
+	typ, _ := feed.DetectDocument(b)
+	switch typ {
+	case feed.DocumentZip:
+		return detectZip(b)
+	case feed.DocumentOLE:
+		return docconv.ConvertDoc, nil
+	case feed.DocumentPDF:
+		return docconv.ConvertPDF, nil
+	case feed.DocumentRTF:
+		return convertRTF, nil
+	case feed.DocumentHTML:
+		return convertHTML, nil
+	}
+	return nil, ErrUnsupportedDocument
+}
+
+// detectZip tells an odt from a docx by their entries
+func detectZip(b []byte) (converter, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, ErrReadingResource(err)
+	}
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return docconv.ConvertDocx, nil
+		case "mimetype":
+			if mimeType(f) == odtMimeType {
+				return docconv.ConvertODT, nil
+			}
+		}
+	}
+	return nil, ErrUnsupportedDocument
+}
+
+func mimeType(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(rc, 128))
+	return string(bytes.TrimSpace(b))
+}
+
+// convertRTF reads the unrtf text as docconv, keeping the short lines it drops, as the days
+func convertRTF(r io.Reader) (string, map[string]string, error) {
+	f, err := docconv.NewLocalFile(r, os.TempDir(), "feeder-rtf-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Done()
+
+	out, err := exec.Command("unrtf", "--nopict", "--text", f.Name()).Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("unrtf error: %v", err)
+	}
+	var b strings.Builder
+	for _, line := range strings.Split(string(out), "\n") {
+		// the comments of unrtf
+		if strings.HasPrefix(line, "###") {
+			continue
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String(), map[string]string{}, nil
+}
+
+// convertHTML renders the body of a page as xhtml, so docconv reads it without tidy
+func convertHTML(r io.Reader) (string, map[string]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if body := htmlBody(doc); body != nil {
+		if err := html.Render(&buf, body); err != nil {
+			return "", nil, err
+		}
+	}
+	return docconv.HTMLToText(&buf), map[string]string{}, nil
+}
+
+// htmlBody returns the body of the page without its scripts and styles
+func htmlBody(n *html.Node) *html.Node {
+	var body *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			switch {
+			case c.Type == html.ElementNode && (c.Data == "script" || c.Data == "style"):
+				n.RemoveChild(c)
+			case c.Type == html.ElementNode && c.Data == "body":
+				body = c
+				walk(c)
+			default:
+				walk(c)
+			}
+			c = next
+		}
+	}
+	walk(n)
+	return body
+}
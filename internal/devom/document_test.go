@@ -0,0 +1,75 @@
+package devom_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDevotionalParser_Formats(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+
+	dp := devom.NewDevotionalParser(*devom.NewAPI(srv.URL))
+	df := feed.NewFeeder(dp, []feed.FileProvider{fs.NewFileProvider()})
+
+	// the converters of the doc, pdf and rtf documents
+	documents := []struct{ file, tool string }{
+		{"./_test_devotionals-ok.odt", ""},
+		{"./_test_devotionals-ok.html", ""},
+		{"./_test_devotionals-ok.doc", "wvText"},
+		{"./_test_devotionals-ok.pdf", "pdftotext"},
+		{"./_test_devotionals-ok.rtf", "unrtf"},
+	}
+	for _, doc := range documents {
+		doc := doc
+		t.Run("it reads valid Feeds from "+doc.file, func(t *testing.T) {
+			if _, err := exec.LookPath(doc.tool); doc.tool != "" && err != nil {
+				t.Skipf("%s is not installed", doc.tool)
+			}
+
+			feeds, err := df.Feeds(context.Background(), doc.file)
+
+			assert.Nil(t, err)
+			assert.Empty(t, feeds.UnknownItems)
+			assert.Equal(t, 3, len(feeds.Items))
+			// the doc paragraphs may end with spaces
+			field := func(i int, key string) string { return strings.TrimSpace(feeds.Items[i][key]) }
+			assert.Equal(t, "001", field(0, "day"))
+			assert.Equal(t, "LA IMPORTANCIA DE LAS ESCRITURAS", field(0, "title"))
+			assert.Equal(t, "(Salmo 119:105).", field(0, "passage_reference"))
+			assert.Equal(t, "Lectura: Deut. 8:2-6.", field(0, "bible_reading"))
+			assert.Equal(t, "SUBIENDO NUESTRA MONTAÑA", field(2, "title"))
+		})
+	}
+
+	t.Run("it reads an html fragment", func(t *testing.T) {
+		feeds, err := dp.Parse(context.Background(), strings.NewReader("<p>001</p><p>EL CAMINO</p>"+
+			"<p>“Lámpara es a mis pies tu palabra” (Salmo 119:105).</p>"+
+			"<p>Lectura: Deut. 8:2-6.</p><p>El nuestro es un compromiso total con la Palabra de Dios.</p>"))
+
+		assert.Nil(t, err)
+		assert.Empty(t, feeds.UnknownItems)
+		assert.Equal(t, "EL CAMINO", feeds.Items[0]["title"])
+	})
+
+	t.Run("it fails with an unsupported document", func(t *testing.T) {
+		_, err := dp.Parse(context.Background(), strings.NewReader("plain text is not a document"))
+
+		assert.Equal(t, devom.ErrUnsupportedDocument, err)
+		assert.Equal(t, feed.ErrCodeUnsupportedFile, feed.Code(err))
+	})
+
+	t.Run("it fails with a zip that is not a document", func(t *testing.T) {
+		_, err := dp.Parse(context.Background(), strings.NewReader("PK\x03\x04 broken"))
+
+		assert.Equal(t, feed.ErrCodeUnreadableFile, feed.Code(err))
+	})
+}
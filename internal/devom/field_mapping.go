@@ -0,0 +1,80 @@
+package devom
+
+import (
+	"fmt"
+	"strings"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+// devotionalKeys are the keys of a devotional item
+var devotionalKeys = []string{"day", "title", "passage_text", "passage_reference", "bible_reading", "content"}
+
+var ErrInvalidFieldMapping = func(spec string) error {
+	return feed.NewError(
+		feed.ErrCodeInvalidRequest,
+		fmt.Errorf("invalid field mapping <%s>, want key=field pairs separated by commas with the keys %s", spec, strings.Join(devotionalKeys, ", ")),
+	)
+}
+
+// FieldMapping names the column or field of each devotional item key, the unmapped keys keep their name
+type FieldMapping map[string]string
+
+// ParseFieldMapping reads a mapping as "day=Dia,title=Titulo,passage_text=passage.text"
+func ParseFieldMapping(spec string) (FieldMapping, error) {
+	m := FieldMapping{}
+	if strings.TrimSpace(spec) == "" {
+		return m, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || !isDevotionalKey(strings.TrimSpace(kv[0])) || strings.TrimSpace(kv[1]) == "" {
+			return nil, ErrInvalidFieldMapping(spec)
+		}
+		m[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return m, nil
+}
+
+// destinationMapping is the mapping of the destination, the fallback one when it has none
+func destinationMapping(d *feed.Destination, fallback FieldMapping) (FieldMapping, error) {
+	if d == nil || strings.TrimSpace(d.FieldMap) == "" {
+		return fallback, nil
+	}
+	return ParseFieldMapping(d.FieldMap)
+}
+
+func (m FieldMapping) field(key string) string {
+	if f, ok := m[key]; ok {
+		return f
+	}
+	return key
+}
+
+// item maps the fields to a devotional, it needs the day, title, passage and content
+func (m FieldMapping) item(value func(field string) string) (feed.Item, error) {
+	dev := feed.Item{}
+	for _, key := range devotionalKeys {
+		dev[key] = strings.TrimSpace(value(m.field(key)))
+	}
+	switch {
+	case dev["day"] == "":
+		return nil, feed.ErrUnknownFeed
+	case dev["title"] == "":
+		return nil, ErrFeedDoesNotHaveTitle
+	case dev["passage_text"] == "":
+		return nil, ErrFeedDoesNotHavePassage
+	case dev["content"] == "":
+		return nil, ErrFeedDoesNotHaveContent
+	}
+	return dev, nil
+}
+
+func isDevotionalKey(key string) bool {
+	for _, k := range devotionalKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package devom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+type jsonParser struct {
+	devotionalParser
+	mapping FieldMapping
+}
+
+// NewJSONDevotionalParser parses JSON dumps of a devotionals array, its fields are the ones of the destination mapping or the m ones,
+// nested fields as "passage.text"
+func NewJSONDevotionalParser(api API, m FieldMapping) feed.Parser {
+	return &jsonParser{devotionalParser{api: api}, m}
+}
+
+func (jp *jsonParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	mapping, err := destinationMapping(jp.to, jp.mapping)
+	if err != nil {
+		return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{}, Items: []feed.Item{}}, err
+	}
+	var devs []map[string]interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&devs); err != nil {
+		return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{}, Items: []feed.Item{}}, ErrReadingResource(err)
+	}
+
+	records := make([]record, 0, len(devs))
+	for _, dev := range devs {
+		dev := dev
+		raw, _ := json.Marshal(dev)
+		records = append(records, record{[]string{string(raw)}, func() (feed.Item, error) {
+			return mapping.item(func(field string) string {
+				return jsonField(dev, field)
+			})
+		}})
+	}
+	return jp.parse(ctx, records)
+}
+
+// jsonField reads the field by its dotted path, as text
+func jsonField(obj map[string]interface{}, path string) string {
+	var v interface{} = obj
+	for _, name := range strings.Split(path, ".") {
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		v = o[name]
+	}
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,59 @@
+package devom_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+const jsonDevotionals = `[
+	{"day": 1, "title": "LA IMPORTANCIA DE LAS ESCRITURAS", "passage": {"text": "“Lámpara es a mis pies tu palabra”", "reference": "(Salmo 119:105)"}, "reading": "Lectura: Deut. 8:2-6.", "content": "El nuestro es un compromiso total."},
+	{"day": 2, "title": "COMPARTIENDO ALEGREMENTE", "passage": {"text": "“Pedro y Juan subían juntos al templo”"}, "content": "Los creyentes se conocían."},
+	{"day": 4, "title": "EL DÍA CUATRO", "passage": {"text": "“Jehová es mi fortaleza”"}, "content": "Con alegría cantamos."}
+]`
+
+func TestJSONDevotionalParser(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+
+	jp := devom.NewJSONDevotionalParser(*devom.NewAPI(srv.URL), devom.FieldMapping{
+		"passage_text":      "passage.text",
+		"passage_reference": "passage.reference",
+		"bible_reading":     "reading",
+	})
+
+	t.Run("it maps the nested fields to the devotional keys", func(t *testing.T) {
+		feeds, err := jp.Parse(context.Background(), strings.NewReader(jsonDevotionals))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(feeds.Items))
+		assert.Equal(t, feed.Item{
+			"day":               "1",
+			"title":             "LA IMPORTANCIA DE LAS ESCRITURAS",
+			"passage_text":      "“Lámpara es a mis pies tu palabra”",
+			"passage_reference": "(Salmo 119:105)",
+			"bible_reading":     "Lectura: Deut. 8:2-6.",
+			"content":           "El nuestro es un compromiso total.",
+		}, feeds.Items[0])
+		assert.Equal(t, "", feeds.Items[1]["passage_reference"])
+	})
+
+	t.Run("it validates the sequential days", func(t *testing.T) {
+		feeds, err := jp.Parse(context.Background(), strings.NewReader(jsonDevotionals))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(feeds.UnknownItems))
+		assert.Equal(t, devom.ErrDoesNotHaveValidDay(3, 4).Error(), feeds.UnknownItems[0].ItemError)
+	})
+
+	t.Run("it fails reading an invalid dump", func(t *testing.T) {
+		_, err := jp.Parse(context.Background(), strings.NewReader(`{"day": 1}`))
+
+		assert.Equal(t, feed.ErrCodeUnreadableFile, feed.Code(err))
+	})
+}
@@ -0,0 +1,60 @@
+package devom
+
+import (
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+// Kind builds the parser and the sender of a kind of feeds, new ones for each import as they keep its destination
+type Kind struct {
+	Name      string
+	NewParser func(api API, m FieldMapping) feed.Parser
+	NewSender func(api API) feed.Sender
+}
+
+// Kinds are the kinds of feeds imported to the devom API, the csv and json devotionals read their fields with the mapping
+var Kinds = []Kind{
+	{
+		Name:      "devotionals",
+		NewParser: func(api API, _ FieldMapping) feed.Parser { return NewDevotionalParser(api) },
+		NewSender: NewDevotionalSender,
+	},
+	{
+		Name:      "markdown-devotionals",
+		NewParser: func(api API, _ FieldMapping) feed.Parser { return NewMarkdownDevotionalParser(api) },
+		NewSender: NewDevotionalSender,
+	},
+	{
+		Name:      "csv-devotionals",
+		NewParser: NewCSVDevotionalParser,
+		NewSender: NewDevotionalSender,
+	},
+	{
+		Name:      "json-devotionals",
+		NewParser: NewJSONDevotionalParser,
+		NewSender: NewDevotionalSender,
+	},
+	{
+		Name:      "topics",
+		NewParser: func(api API, _ FieldMapping) feed.Parser { return NewTopicParser(api) },
+		NewSender: NewTopicSender,
+	},
+}
+
+// KindOf returns the kind of feeds named so
+func KindOf(name string) (Kind, error) {
+	for _, k := range Kinds {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Kind{}, feed.ErrUnknownKind
+}
+
+// KindNames returns the names of the kinds of feeds
+func KindNames() []string {
+	names := make([]string, 0, len(Kinds))
+	for _, k := range Kinds {
+		names = append(names, k.Name)
+	}
+	return names
+}
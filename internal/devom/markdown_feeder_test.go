@@ -0,0 +1,63 @@
+package devom_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownDevotionalFeeder_FS(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+
+	mp := devom.NewMarkdownDevotionalParser(*devom.NewAPI(srv.URL))
+	df := feed.NewFeeder(mp, []feed.FileProvider{fs.NewFileProvider()})
+
+	t.Run("it reads valid Feeds with the Word document keys", func(t *testing.T) {
+		feeds, err := df.Feeds(context.Background(), "./_test_devotionals-ok.md")
+
+		assert.Nil(t, err)
+		assert.Empty(t, feeds.UnknownItems)
+		assert.Equal(t, 3, len(feeds.Items))
+		assert.Equal(t, feed.Item{
+			"day":               "001",
+			"title":             "LA IMPORTANCIA DE LAS ESCRITURAS",
+			"passage_text":      "“Lámpara es a mis pies tu palabra, y lumbrera a mi camino”",
+			"passage_reference": "(Salmo 119:105).",
+			"bible_reading":     "Lectura: Deut. 8:2-6.",
+			"content":           "El nuestro es un compromiso total con la importancia y la centralidad de la Palabra de Dios.\n\nLeer y estudiar la Biblia no es una opción, sino un mandamiento de Dios.\n\n",
+		}, feeds.Items[0])
+		assert.Equal(t, "\"Pedro y Juan subían juntos al templo a la hora novena, la de la oración.\n\nY era traído un hombre cojo de nacimiento.”", feeds.Items[1]["passage_text"])
+		assert.Equal(t, "", feeds.Items[1]["bible_reading"])
+	})
+
+	t.Run("it reads Feeds with UnknownFeeds", func(t *testing.T) {
+		feeds, err := df.Feeds(context.Background(), "./_test_devotionals-ko.md")
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(feeds.UnknownItems))
+		assert.Equal(t, devom.ErrFeedDoesNotHavePassage.Error(), feeds.UnknownItems[0].ItemError)
+		assert.Equal(t, devom.ErrFeedDoesNotHaveValidPassage.Error(), feeds.UnknownItems[1].ItemError)
+		assert.Equal(t, devom.ErrFeedDoesNotHaveContent.Error(), feeds.UnknownItems[2].ItemError)
+		assert.Equal(t, 2, len(feeds.Items))
+		assert.Equal(t, "EL DÍA CUATRO", feeds.Items[0]["title"])
+	})
+
+	t.Run("it does not take the passage as the title", func(t *testing.T) {
+		feeds, err := mp.Parse(context.Background(), strings.NewReader("## 001\n\n"+
+			"> “Lámpara es a mis pies tu palabra” (Salmo 119:105).\n\n"+
+			"Lectura: Deut. 8:2-6.\n\n"+
+			"El nuestro es un compromiso total con la Palabra de Dios.\n"))
+
+		assert.Nil(t, err)
+		assert.Empty(t, feeds.Items)
+		assert.Equal(t, 1, len(feeds.UnknownItems))
+		assert.Equal(t, devom.ErrFeedDoesNotHaveTitle.Error(), feeds.UnknownItems[0].ItemError)
+	})
+}
@@ -0,0 +1,142 @@
+package devom
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+// dayHeading starts each devotional of a Markdown document, as "## 001"
+var dayHeading = regexp.MustCompile(`(?m)^##[ \t]+([0-9]+)[ \t]*$`)
+
+type markdownParser struct {
+	devotionalParser
+}
+
+// NewMarkdownDevotionalParser parses Markdown documents, each devotional is a "## <day>" heading
+// followed by its title, the passage blockquote, the "Lectura:" line and the content paragraphs
+func NewMarkdownDevotionalParser(api API) feed.Parser {
+	return &markdownParser{devotionalParser{api: api}}
+}
+
+func (mp *markdownParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{}, Items: []feed.Item{}}, ErrReadingResource(err)
+	}
+
+	return mp.parse(ctx, textRecords(splitMarkdown(string(b)), parseMarkdownDevotional))
+}
+
+// splitMarkdown splits the devotionals by their day heading, as "<day>\n<text>",
+// skipping the text before the first one
+func splitMarkdown(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	headings := dayHeading.FindAllStringSubmatchIndex(text, -1)
+
+	var devs []string
+	for i, h := range headings {
+		end := len(text)
+		if i+1 < len(headings) {
+			end = headings[i+1][0]
+		}
+		devs = append(devs, text[h[2]:h[3]]+"\n"+text[h[1]:end])
+	}
+	return devs
+}
+
+// parseMarkdownDevotional reads the day, title, passage, bible reading and content paragraphs
+// with the same keys as the Word documents
+func parseMarkdownDevotional(text string) (feed.Item, error) {
+	blocks := paragraphs(text)
+	if len(blocks) < 3 {
+		return nil, feed.ErrUnknownFeed
+	}
+	// the passage quote right after the day leaves the devotional without title
+	if blocks[1].quote {
+		return nil, ErrFeedDoesNotHaveTitle
+	}
+
+	dev := make(map[string]string)
+	dev["day"] = blocks[0].text
+	dev["title"] = title(blocks[1].text)
+	dev["bible_reading"] = ""
+
+	var passageLines []string
+	content := ""
+	for _, b := range blocks[2:] {
+		switch {
+		case b.quote && content == "" && dev["bible_reading"] == "":
+			passageLines = append(passageLines, b.text)
+		case isBibleReading(b.text) && dev["bible_reading"] == "":
+			dev["bible_reading"] = unemphasize(b.text)
+		default:
+			content += b.text + "\n\n"
+		}
+	}
+
+	if len(passageLines) == 0 {
+		return nil, ErrFeedDoesNotHavePassage
+	}
+	if content == "" {
+		return nil, ErrFeedDoesNotHaveContent
+	}
+	passage, err := passage(passageLines, 0, len(passageLines)-1)
+	if err != nil {
+		return nil, err
+	}
+	dev["passage_text"], dev["passage_reference"] = passage.Text, passage.Reference
+	dev["content"] = content
+
+	return dev, nil
+}
+
+// paragraph is a Markdown paragraph with its wrapped lines joined, quote tells it is a blockquote one
+type paragraph struct {
+	text  string
+	quote bool
+}
+
+func paragraphs(text string) []paragraph {
+	var ps []paragraph
+	var lines []string
+	quote := false
+	flush := func() {
+		if len(lines) > 0 {
+			ps = append(ps, paragraph{strings.Join(lines, " "), quote})
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		isQuote := strings.HasPrefix(line, ">")
+		if isQuote {
+			line = strings.TrimSpace(strings.TrimLeft(line, ">"))
+		}
+		if line == "" || isQuote != quote {
+			flush()
+			quote = isQuote
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return ps
+}
+
+// title drops the heading marks and the emphasis of the title line
+func title(line string) string {
+	return unemphasize(strings.TrimSpace(strings.TrimLeft(line, "#")))
+}
+
+var emphasis = strings.NewReplacer("**", "", "__", "")
+
+func unemphasize(line string) string {
+	return strings.TrimSpace(emphasis.Replace(line))
+}
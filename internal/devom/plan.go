@@ -15,3 +15,12 @@ type Plan struct {
 	PublisherId      string                      `json:"publisherId"`
 	DailyDevotionals map[string]*DailyDevotional `json:"-"`
 }
+
+func (p Plan) hasDevotional(devotionalId string) bool {
+	for _, dd := range p.DailyDevotionals {
+		if dd.Devotional.Id == devotionalId {
+			return true
+		}
+	}
+	return false
+}
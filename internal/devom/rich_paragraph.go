@@ -0,0 +1,254 @@
+package devom
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/unidoc/unioffice"
+	"github.com/unidoc/unioffice/document"
+	"github.com/unidoc/unioffice/schema/soo/wml"
+)
+
+func init() {
+	// unioffice logs the docx parts it does not read, as custom xml
+	unioffice.DisableLogging()
+}
+
+// quoteStyles are the Word paragraph styles of the quotes
+var quoteStyles = map[string]bool{
+	"Quote":         true,
+	"IntenseQuote":  true,
+	"Cita":          true,
+	"Citadestacada": true,
+}
+
+// dayParagraph starts a devotional
+var dayParagraph = regexp.MustCompile(`^[0-9]+$`)
+
+// span is a run of text sharing its emphasis
+type span struct {
+	text         string
+	bold, italic bool
+}
+
+// richParagraph is a line of a document with the emphasis of its text
+type richParagraph struct {
+	spans       []span
+	list, quote bool
+}
+
+func (p richParagraph) text() string {
+	var b strings.Builder
+	for _, s := range p.spans {
+		b.WriteString(s.text)
+	}
+	return b.String()
+}
+
+// until keeps the text of the paragraph before the offset
+func (p richParagraph) until(offset int) richParagraph {
+	cut := richParagraph{list: p.list, quote: p.quote}
+	for _, s := range p.spans {
+		if offset <= 0 {
+			break
+		}
+		if len(s.text) > offset {
+			s.text = s.text[:offset]
+		}
+		offset -= len(s.text)
+		cut.spans = append(cut.spans, s)
+	}
+	return cut
+}
+
+// readParagraphs reads the paragraphs of a docx with their emphasis, the lines of other documents are plain paragraphs
+func readParagraphs(r io.Reader) ([]richParagraph, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, ErrReadingResource(err)
+	}
+	if isDocx(b) {
+		return docxParagraphs(b)
+	}
+
+	txt, err := readDocument(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	ps := []richParagraph{}
+	for _, line := range lines(txt) {
+		ps = append(ps, richParagraph{spans: []span{{text: line}}})
+	}
+	return ps, nil
+}
+
+func isDocx(b []byte) bool {
+	if typ, _ := feed.DetectDocument(b); typ != feed.DocumentZip {
+		return false
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// docxParagraphs splits the docx paragraphs on their line breaks, as the plain text does
+func docxParagraphs(b []byte) ([]richParagraph, error) {
+	doc, err := document.Read(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, ErrReadingResource(err)
+	}
+
+	ps := []richParagraph{}
+	for _, dp := range doc.Paragraphs() {
+		p := richParagraph{
+			list:  dp.X().PPr != nil && dp.X().PPr.NumPr != nil,
+			quote: quoteStyles[dp.Style()],
+		}
+		for _, r := range runs(dp.X()) {
+			s := span{bold: on(r.RPr, boldProperty), italic: on(r.RPr, italicProperty)}
+			for _, ic := range r.EG_RunInnerContent {
+				switch {
+				case ic.T != nil:
+					s.text += ic.T.Content
+				case ic.Tab != nil:
+					s.text += " "
+				case ic.Br != nil, ic.Cr != nil:
+					p.spans = append(p.spans, s)
+					ps = appendParagraph(ps, p)
+					p.spans, s.text = nil, ""
+				}
+			}
+			p.spans = append(p.spans, s)
+		}
+		ps = appendParagraph(ps, p)
+	}
+	return ps, nil
+}
+
+// appendParagraph skips the blank paragraphs, as lines skips the blank lines
+func appendParagraph(ps []richParagraph, p richParagraph) []richParagraph {
+	if strings.TrimSpace(p.text()) == "" {
+		return ps
+	}
+	return append(ps, p)
+}
+
+// runs are the runs of the paragraph, with the ones of its links and content controls
+func runs(p *wml.CT_P) []*wml.CT_R {
+	rs := []*wml.CT_R{}
+	add := func(rcs []*wml.EG_ContentRunContent) {
+		for _, rc := range rcs {
+			if rc.R != nil {
+				rs = append(rs, rc.R)
+			}
+			if rc.Sdt != nil && rc.Sdt.SdtContent != nil {
+				for _, sdt := range rc.Sdt.SdtContent.EG_ContentRunContent {
+					if sdt.R != nil {
+						rs = append(rs, sdt.R)
+					}
+				}
+			}
+		}
+	}
+	for _, c := range p.EG_PContent {
+		add(c.EG_ContentRunContent)
+		if c.Hyperlink != nil {
+			add(c.Hyperlink.EG_ContentRunContent)
+		}
+	}
+	return rs
+}
+
+func boldProperty(rpr *wml.CT_RPr) *wml.CT_OnOff {
+	return rpr.B
+}
+
+func italicProperty(rpr *wml.CT_RPr) *wml.CT_OnOff {
+	return rpr.I
+}
+
+// on tells whether the run property is set, and not turned off
+func on(rpr *wml.CT_RPr, property func(*wml.CT_RPr) *wml.CT_OnOff) bool {
+	if rpr == nil {
+		return false
+	}
+	v := property(rpr)
+	if v == nil {
+		return false
+	}
+	return v.ValAttr == nil || v.ValAttr.Bool == nil || *v.ValAttr.Bool
+}
+
+// splitParagraphs splits the paragraphs by the day starting each devotional, skipping the heading of the document
+func splitParagraphs(ps []richParagraph) [][]richParagraph {
+	devs := [][]richParagraph{}
+	for _, p := range ps {
+		if dayParagraph.MatchString(strings.TrimSpace(p.text())) {
+			devs = append(devs, []richParagraph{p})
+			continue
+		}
+		if len(devs) > 0 {
+			devs[len(devs)-1] = append(devs[len(devs)-1], p)
+		}
+	}
+	return devs
+}
+
+// richRecords parses each devotional paragraphs writing its rich text with rt
+func richRecords(devs [][]richParagraph, rt richText) []record {
+	records := make([]record, 0, len(devs))
+	for _, dev := range devs {
+		dev := dev
+		records = append(records, record{texts(dev), func() (feed.Item, error) { return parseRichDevotional(dev, rt) }})
+	}
+	return records
+}
+
+// texts are the plain lines of the paragraphs, with the day trimmed as the plain text one
+func texts(ps []richParagraph) []string {
+	lines := make([]string, 0, len(ps))
+	for _, p := range ps {
+		lines = append(lines, p.text())
+	}
+	if len(lines) > 0 {
+		lines[0] = strings.TrimSpace(lines[0])
+	}
+	return lines
+}
+
+func parseRichDevotional(ps []richParagraph, rt richText) (feed.Item, error) {
+	lines := texts(ps)
+	dev, s, err := devotionalSections(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.passageStart == s.passageEnd {
+		_, reference, err := splitPassage(lines[s.passageStart])
+		if err != nil {
+			return nil, err
+		}
+		p := ps[s.passageStart]
+		if reference != "" {
+			p = p.until(lastPassageChar.FindStringSubmatchIndex(lines[s.passageStart])[3])
+		}
+		dev["passage_text"], dev["passage_reference"] = rt.passage([]richParagraph{p}), reference
+	} else {
+		dev["passage_text"], dev["passage_reference"] = rt.passage(ps[s.passageStart:s.passageEnd+1]), ""
+	}
+	dev["content"] = rt.content(ps[s.contentStart:])
+
+	return dev, nil
+}
@@ -0,0 +1,164 @@
+package devom
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+// richText writes the paragraphs of a devotional with their emphasis, lists and quotes
+type richText interface {
+	content(ps []richParagraph) string
+	passage(ps []richParagraph) string
+}
+
+var richTexts = map[feed.Format]richText{
+	feed.FormatMarkdown: markdownText{},
+	feed.FormatHTML:     htmlText{},
+}
+
+// markdownEscaper escapes the emphasis, code, html and link characters
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, `*`, `\*`, `_`, `\_`, "`", "\\`",
+	`<`, `\<`, `>`, `\>`, `&`, `\&`, `[`, `\[`,
+)
+
+// markdownBlockMarker starts a heading, quote, list or numbered list line
+var markdownBlockMarker = regexp.MustCompile(`^(#|>|-|\+|[0-9]+[.)])`)
+
+// markdownText writes each paragraph as a Markdown block, the content ends each one with a blank line as the plain text
+type markdownText struct{}
+
+func (markdownText) content(ps []richParagraph) string {
+	var b strings.Builder
+	for _, p := range ps {
+		b.WriteString(markdownBlock(p) + "\n\n")
+	}
+	return b.String()
+}
+
+func (markdownText) passage(ps []richParagraph) string {
+	blocks := make([]string, 0, len(ps))
+	for _, p := range ps {
+		blocks = append(blocks, markdownBlock(p))
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+func markdownBlock(p richParagraph) string {
+	txt := escapeBlockMarker(inline(p, markdownEscaper.Replace, "**", "**", "*", "*"))
+	switch {
+	case p.list:
+		return "- " + txt
+	case p.quote:
+		return "> " + txt
+	}
+	return txt
+}
+
+// escapeBlockMarker escapes the block marker starting the paragraph, so it stays as text
+func escapeBlockMarker(txt string) string {
+	m := markdownBlockMarker.FindString(txt)
+	if m == "" {
+		return txt
+	}
+	return m[:len(m)-1] + `\` + txt[len(m)-1:]
+}
+
+// htmlText writes the paragraphs as escaped html, with the only tags p, ul, li, blockquote, strong and em
+type htmlText struct{}
+
+func (htmlText) content(ps []richParagraph) string {
+	return htmlBlocks(ps)
+}
+
+func (htmlText) passage(ps []richParagraph) string {
+	return htmlBlocks(ps)
+}
+
+// htmlBlocks groups the consecutive list items and quotes
+func htmlBlocks(ps []richParagraph) string {
+	var b strings.Builder
+	for i, p := range ps {
+		txt := inline(p, html.EscapeString, "<strong>", "</strong>", "<em>", "</em>")
+		if p.list {
+			txt = "<li>" + txt + "</li>"
+		} else {
+			txt = "<p>" + txt + "</p>"
+		}
+		first := i == 0 || !sameBlock(ps[i-1], p)
+		last := i == len(ps)-1 || !sameBlock(ps[i+1], p)
+		switch {
+		case p.list && first:
+			txt = "<ul>" + txt
+		case p.quote && first:
+			txt = "<blockquote>" + txt
+		}
+		switch {
+		case p.list && last:
+			txt += "</ul>"
+		case p.quote && last:
+			txt += "</blockquote>"
+		}
+		b.WriteString(txt)
+		if last {
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sameBlock tells both paragraphs are items of the same list or lines of the same quote
+func sameBlock(p, q richParagraph) bool {
+	return (p.list || p.quote) && p.list == q.list && p.quote == q.quote
+}
+
+// inline writes the escaped text of the paragraph, marking the spans with the same emphasis,
+// with their surrounding spaces out of the marks
+func inline(p richParagraph, escape func(string) string, boldOpen, boldClose, italicOpen, italicClose string) string {
+	var b strings.Builder
+	spans := merge(p.spans)
+	for i, s := range spans {
+		txt := s.text
+		if i == 0 {
+			txt = strings.TrimLeftFunc(txt, unicode.IsSpace)
+		}
+		if i == len(spans)-1 {
+			txt = strings.TrimRightFunc(txt, unicode.IsSpace)
+		}
+		trimmed := strings.TrimSpace(txt)
+		if trimmed == "" || (!s.bold && !s.italic) {
+			b.WriteString(escape(txt))
+			continue
+		}
+		open, close := "", ""
+		if s.bold {
+			open, close = open+boldOpen, boldClose+close
+		}
+		if s.italic {
+			open, close = open+italicOpen, italicClose+close
+		}
+		start := strings.Index(txt, trimmed)
+		b.WriteString(escape(txt[:start]) + open + escape(trimmed) + close + escape(txt[start+len(trimmed):]))
+	}
+	return b.String()
+}
+
+// merge joins the consecutive spans with the same emphasis, and the blank ones to the previous span
+func merge(spans []span) []span {
+	merged := []span{}
+	for _, s := range spans {
+		if s.text == "" {
+			continue
+		}
+		if n := len(merged); n > 0 && (strings.TrimSpace(s.text) == "" || merged[n-1].bold == s.bold && merged[n-1].italic == s.italic) {
+			merged[n-1].text += s.text
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
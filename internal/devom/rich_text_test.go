@@ -0,0 +1,145 @@
+package devom_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/unidoc/unioffice/document"
+	"github.com/unidoc/unioffice/schema/soo/wml"
+)
+
+func TestDevotionalParser_RichText(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+
+	docx := richDocx(t)
+	parse := func(t *testing.T, format feed.Format, b []byte) *feed.ParsedItems {
+		dp := devom.NewDevotionalParser(*devom.NewAPI(srv.URL))
+		d := feed.NewDestination("plan", "publisher", "author")
+		d.Format = format
+		dp.Destination(d)
+		feeds, err := dp.Parse(context.Background(), bytes.NewReader(b))
+		assert.Nil(t, err)
+		assert.Empty(t, feeds.UnknownItems)
+		return feeds
+	}
+
+	t.Run("it writes the docx emphasis, lists and quotes in Markdown", func(t *testing.T) {
+		feeds := parse(t, feed.FormatMarkdown, docx)
+
+		assert.Equal(t, feed.Item{
+			"day":               "001",
+			"title":             "EL PASAJE",
+			"passage_text":      "*“Porque de tal manera amó Dios al mundo”*",
+			"passage_reference": "(Juan 3:16).",
+			"bible_reading":     "Lectura: Juan 3:1-21.",
+			"content":           "Un **amor** sin *medida* y \\<sin\\> \\*reservas\\* \\[\\& más].\n\n1\\. Sin lista\n\n\\# Sin título\n\n- Primero\n\n- Segundo\n\n> Una cita\n\n",
+		}, feeds.Items[0])
+	})
+
+	t.Run("it writes the docx emphasis, lists and quotes in sanitized HTML", func(t *testing.T) {
+		feeds := parse(t, feed.FormatHTML, docx)
+
+		assert.Equal(t, "<p><em>“Porque de tal manera amó Dios al mundo”</em></p>", feeds.Items[0]["passage_text"])
+		assert.Equal(t, "(Juan 3:16).", feeds.Items[0]["passage_reference"])
+		assert.Equal(t, "<p>Un <strong>amor</strong> sin <em>medida</em> y &lt;sin&gt; *reservas* [&amp; más].</p>\n"+
+			"<p>1. Sin lista</p>\n<p># Sin título</p>\n"+
+			"<ul><li>Primero</li><li>Segundo</li></ul>\n"+
+			"<blockquote><p>Una cita</p></blockquote>", feeds.Items[0]["content"])
+	})
+
+	t.Run("it keeps the plain text by default", func(t *testing.T) {
+		feeds := parse(t, feed.FormatText, docx)
+
+		assert.Equal(t, "“Porque de tal manera amó Dios al mundo”", feeds.Items[0]["passage_text"])
+		assert.Equal(t, "Un amor sin medida y <sin> *reservas* [& más].\n\n1. Sin lista\n\n# Sin título\n\nPrimero\n\nSegundo\n\nUna cita\n\n", feeds.Items[0]["content"])
+	})
+
+	t.Run("it rejects the rich text for the kinds that do not write it", func(t *testing.T) {
+		api := *devom.NewAPI(srv.URL)
+		for _, p := range []feed.Parser{devom.NewMarkdownDevotionalParser(api), devom.NewTopicParser(api)} {
+			d := feed.NewDestination("plan", "publisher", "author")
+			d.Format = feed.FormatHTML
+			df := feed.NewFeeder(p, []feed.FileProvider{fs.NewFileProvider()})
+			df.Destination(d)
+
+			_, err := df.Feeds(context.Background(), "./_test_devotionals-ok.md")
+
+			assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+		}
+	})
+
+	t.Run("it reads the same devotionals of the Word documents in every format", func(t *testing.T) {
+		b, err := ioutil.ReadFile("./_test_devotionals-ok.docx")
+		assert.Nil(t, err)
+		text := parse(t, feed.FormatText, b)
+
+		for _, format := range []feed.Format{feed.FormatMarkdown, feed.FormatHTML} {
+			feeds := parse(t, format, b)
+
+			assert.Equal(t, len(text.Items), len(feeds.Items))
+			for i, item := range feeds.Items {
+				for _, key := range []string{"day", "title", "passage_reference", "bible_reading"} {
+					assert.Equal(t, text.Items[i][key], item[key], key)
+				}
+			}
+		}
+	})
+
+	t.Run("it writes the paragraphs of other documents as plain blocks", func(t *testing.T) {
+		b, err := ioutil.ReadFile("./_test_devotionals-ok.odt")
+		assert.Nil(t, err)
+
+		feeds := parse(t, feed.FormatHTML, b)
+
+		assert.Equal(t, 3, len(feeds.Items))
+		assert.Equal(t, "<p>“Lámpara es a mis pies tu palabra, y lumbrera a mi camino”</p>", feeds.Items[0]["passage_text"])
+		assert.Equal(t, "<p>El nuestro es un compromiso total con la importancia y la centralidad de la Palabra de Dios.</p>\n"+
+			"<p>Leer y estudiar la Biblia no es una opción, sino un mandamiento de Dios.</p>", feeds.Items[0]["content"])
+	})
+}
+
+// richDocx writes a Word document with a devotional using emphasis, a list and a quote
+func richDocx(t *testing.T) []byte {
+	doc := document.New()
+	text := func(p document.Paragraph, txt string) document.Run {
+		r := p.AddRun()
+		r.AddText(txt)
+		return r
+	}
+
+	text(doc.AddParagraph(), "Devocionales 2021")
+	text(doc.AddParagraph(), "001")
+	text(doc.AddParagraph(), "EL PASAJE")
+	p := doc.AddParagraph()
+	text(p, "“Porque de tal manera amó Dios al mundo” ").Properties().SetItalic(true)
+	text(p, "(Juan 3:16).")
+	text(doc.AddParagraph(), "Lectura: Juan 3:1-21.")
+	p = doc.AddParagraph()
+	text(p, "Un ")
+	text(p, "amor ").Properties().SetBold(true)
+	text(p, "sin ")
+	text(p, "medida").Properties().SetItalic(true)
+	text(p, " y <sin> *reservas* [& más].")
+	text(doc.AddParagraph(), "1. Sin lista")
+	text(doc.AddParagraph(), "# Sin título")
+	for _, item := range []string{"Primero", "Segundo"} {
+		p = doc.AddParagraph()
+		p.Properties().X().NumPr = wml.NewCT_NumPr()
+		text(p, item)
+	}
+	p = doc.AddParagraph()
+	p.SetStyle("Quote")
+	text(p, "Una cita")
+
+	var b bytes.Buffer
+	assert.Nil(t, doc.Save(&b))
+	return b.Bytes()
+}
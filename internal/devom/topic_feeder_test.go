@@ -2,7 +2,6 @@ package devom_test
 
 import (
 	"context"
-	"log"
 	"os"
 	"testing"
 
@@ -29,7 +28,7 @@ func TestTopicFeeder_FS(t *testing.T) {
 	df := feed.NewFeeder(dp, []feed.FileProvider{fp})
 
 	t.Run("it parses Feed with UnknownFeeds", func(t *testing.T) {
-		feeds, err := df.Feeds(feedSource["topics-ko"])
+		feeds, err := df.Feeds(context.Background(), feedSource["topics-ko"])
 
 		assert.Nil(t, err)
 		assert.Equal(t, 2, len(feeds.Items))
@@ -37,14 +36,14 @@ func TestTopicFeeder_FS(t *testing.T) {
 	})
 
 	t.Run("it fails read Feed without resource file", func(t *testing.T) {
-		feeds, err := df.Feeds(feedSource["no-file"])
+		feeds, err := df.Feeds(context.Background(), feedSource["no-file"])
 
 		assert.NotNil(t, err)
 		assert.Nil(t, feeds)
 	})
 
 	t.Run("it parses Feed", func(t *testing.T) {
-		feeds, err := df.Feeds(feedSource["topics-ok"])
+		feeds, err := df.Feeds(context.Background(), feedSource["topics-ok"])
 
 		assert.Nil(t, err)
 		assert.Equal(t, 7, len(feeds.Items))
@@ -55,7 +54,7 @@ func TestTopicFeeder_FS(t *testing.T) {
 func TestTopicFeeder_GD(t *testing.T) {
 	googleAPIKey := os.Getenv("GOOGLE_API_KEY")
 	if googleAPIKey == "" {
-		log.Fatal("ERROR: you must provide a Google Api Key")
+		t.Skip("you must provide a Google Api Key")
 	}
 
 	ctx := context.Background()
@@ -66,7 +65,7 @@ func TestTopicFeeder_GD(t *testing.T) {
 	df := feed.NewFeeder(dp, []feed.FileProvider{fp})
 
 	t.Run("it parses from Google Drive", func(t *testing.T) {
-		feeds, err := df.Feeds(feedSource["drive-topics-ok"])
+		feeds, err := df.Feeds(context.Background(), feedSource["drive-topics-ok"])
 
 		assert.Nil(t, err)
 		assert.Equal(t, 7, len(feeds.Items))
@@ -1,6 +1,7 @@
 package devom
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -42,7 +43,7 @@ func (dp *TopicParser) Destination(d *feed.Destination) {
 	dp.to = d
 }
 
-func (dp *TopicParser) Parse(r io.Reader) (*feed.ParsedItems, error) {
+func (dp *TopicParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
 	feeds := []feed.Item{}
 	unknownFeeds := []feed.UnknownItem{}
 
@@ -53,19 +54,22 @@ func (dp *TopicParser) Parse(r io.Reader) (*feed.ParsedItems, error) {
 
 	rows, err := f.GetRows("Traspuesto")
 	if err != nil {
-		return nil, err
+		return nil, ErrReadingResource(err)
 	}
 
 	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		item, err := parseFeedItem(row)
 		if err != nil {
-			unknownFeeds = append(unknownFeeds, feed.UnknownItem{row, err.Error()})
+			unknownFeeds = append(unknownFeeds, feed.UnknownItem{Item: row, ItemError: err.Error()})
 			continue
 		}
 
 		feeds = append(feeds, item)
 	}
-	return &feed.ParsedItems{unknownFeeds, feeds}, nil
+	return &feed.ParsedItems{UnknownItems: unknownFeeds, Items: feeds}, nil
 }
 
 func parseFeedItem(row []string) (feed.Item, error) {
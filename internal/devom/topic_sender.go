@@ -1,10 +1,10 @@
 package devom
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -12,18 +12,27 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	kindTopic           = "topic"
+	kindTopicPlan       = "topic_plan"
+	kindDevotionalTopic = "devotional_topic"
+	kindPlanDevotional  = "plan_devotional"
+)
+
 var (
-	ErrImportingTopics = errors.New("fails importing topics")
+	ErrImportingTopics = func(code feed.ErrorCode) error {
+		return feed.NewError(code, errors.New("fails importing topics"))
+	}
 
+	ErrInvalidYearlyDevotionals = func(err error) error {
+		return feed.NewError(feed.ErrCodeUnknownFeed, fmt.Errorf("Invalid topic devotionals: %w", err))
+	}
 	ErrDailyDevotionalNotFound = func(planId string, day int) error {
 		return fmt.Errorf("Daily Devotional not found <%s : %d> not found", planId, day)
 	}
 	ErrYearlyPlanNotFound = func(year int) error {
 		return fmt.Errorf("Plan <%d> not found", year)
 	}
-	ErrTopicPlanNotFound = func(topicId string) error {
-		return fmt.Errorf("Plan <%s> not found", topicId)
-	}
 )
 
 type TopicSender struct {
@@ -41,33 +50,73 @@ func (ts *TopicSender) Destination(d *feed.Destination) {
 	ts.to = d
 }
 
-func (ts *TopicSender) Send(items []feed.Item) error {
-	if err := ts.refreshCache(ts.to.AuthorId); err != nil {
-		log.Fatal(err)
-		return nil
+func (ts *TopicSender) Send(ctx context.Context, items []feed.Item) (*feed.Report, error) {
+	return ts.send(ctx, items, false)
+}
+
+func (ts *TopicSender) Plan(ctx context.Context, items []feed.Item) (*feed.Report, error) {
+	return ts.send(ctx, items, true)
+}
+
+// send creates the topics and their plans, on dry-run it only reports the changes
+func (ts *TopicSender) send(ctx context.Context, items []feed.Item, dryRun bool) (*feed.Report, error) {
+	if err := ts.refreshCache(ctx, ts.to.AuthorId); err != nil {
+		return nil, ErrLoadingDestination(err)
 	}
 
-	var errors []error
+	report := feed.NewReport()
+	var errs []error
 	for _, item := range items {
-		topic := ts.topic(topicTitle(item))
-		if topic == nil {
-			topic = ts.mapItem(item)
-			//create new topic
-			if err := ts.api.createTopic(*topic); err != nil {
-				return err
-			}
-			ts.topics[topic.Title] = topic
+		if err := ctx.Err(); err != nil {
+			return report, err
 		}
-
-		//categorize devotionals
-		err := ts.addTopicToDevotionals(*topic, item["devotionals"])
+		changes, outcome, err := ts.sendItem(ctx, item, dryRun)
+		report.Add(item, outcome, changes, err)
 		if err != nil {
-			errors = append(errors, err)
-			continue
+			errs = append(errs, err)
 		}
+	}
+
+	if len(errs) == 0 || dryRun {
+		return report, nil
+	}
+	return report, ErrImportingTopics(failureCode(errs))
+}
 
-		//create topic plan
-		topicPlan := &Plan{
+// sendItem creates the item topic and its plan and returns the changes and the outcome
+func (ts *TopicSender) sendItem(ctx context.Context, item feed.Item, dryRun bool) ([]feed.Change, feed.Outcome, error) {
+	var changes []feed.Change
+	// an existing topic is skipped, its changes tell what is attached to it
+	outcome := feed.OutcomeSkipped
+	topic := ts.topic(topicTitle(item))
+	if topic == nil {
+		topic = ts.mapItem(item)
+		outcome = feed.OutcomeCreated
+		changes = append(changes, feed.Change{Action: feed.ActionCreate, Kind: kindTopic, Title: topic.Title})
+		//create new topic
+		if !dryRun {
+			if err := ts.api.createTopic(ctx, *topic); err != nil {
+				return changes, outcome, err
+			}
+		}
+		ts.topics[topic.Title] = topic
+	} else {
+		changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindTopic, Title: topic.Title})
+	}
+
+	//categorize devotionals
+	devChanges, err := ts.addTopicToDevotionals(ctx, *topic, item["devotionals"], dryRun)
+	changes = append(changes, devChanges...)
+	if err != nil {
+		return changes, outcome, err
+	}
+
+	//create topic plan, reusing the one of a previous import
+	topicPlan, ok := ts.plans[topic.Id]
+	if ok {
+		changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindTopicPlan, Title: topicPlan.Title})
+	} else {
+		topicPlan = &Plan{
 			Id:          uuid.New().String(),
 			Title:       planTitle(item),
 			Description: "",
@@ -75,77 +124,109 @@ func (ts *TopicSender) Send(items []feed.Item) error {
 			AuthorId:    ts.to.AuthorId,
 			PublisherId: ts.to.PublisherId,
 		}
-		err = ts.api.createPlan(*topicPlan)
-		if err != nil {
-			return err
+		changes = append(changes, feed.Change{Action: feed.ActionCreate, Kind: kindTopicPlan, Title: topicPlan.Title})
+		if !dryRun {
+			if err := ts.api.createPlan(ctx, *topicPlan); err != nil {
+				return changes, outcome, err
+			}
 		}
 		ts.plans[topicPlan.TopicId] = topicPlan
-
-		//add devotionals to the topic plan
-		err = ts.addDailyDevotionals(*topicPlan, item["devotionals"])
-		if err != nil {
-			errors = append(errors, err)
-			continue
-		}
 	}
 
-	if errors == nil {
-		return nil
-	}
-	return ErrImportingTopics
+	//add devotionals to the topic plan
+	planChanges, err := ts.addDailyDevotionals(ctx, *topicPlan, item["devotionals"], dryRun)
+	changes = append(changes, planChanges...)
+	return changes, outcome, err
 }
 
-func (ts *TopicSender) addDailyDevotionals(plan Plan, yearlyDevotionalsJSON string) error {
+// addDailyDevotionals adds the yearly devotionals to the plan, it returns the first failure
+func (ts *TopicSender) addDailyDevotionals(ctx context.Context, plan Plan, yearlyDevotionalsJSON string, dryRun bool) ([]feed.Change, error) {
 
-	yealyDevotionals := ts.mapYearlyDevotionalsFromJSON(yearlyDevotionalsJSON)
-	var err error
+	yealyDevotionals, err := ts.mapYearlyDevotionalsFromJSON(yearlyDevotionalsJSON)
+	if err != nil {
+		return []feed.Change{{Action: feed.ActionSkip, Kind: kindPlanDevotional, Title: plan.Title, Detail: err.Error()}}, err
+	}
+
+	var changes []feed.Change
+	var failure error
 	for _, dev := range yealyDevotionals {
 		yearlyPlan := ts.yearlyPlan(GetYearlyPlanReq{Year: dev.Year, AuthorId: ts.to.AuthorId})
 		if yearlyPlan == nil {
 			err = ErrYearlyPlanNotFound(dev.Year)
-			log.Println(err)
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindPlanDevotional, Title: plan.Title, Day: dev.Day, Detail: err.Error()})
+			failure = firstError(failure, err)
 			continue
 		}
 
 		dd := ts.dailyDevotional(GetPlanDevotionalReq{TopicId: yearlyPlan.TopicId, Day: dev.Day})
 		if dd == nil {
 			err = ErrDailyDevotionalNotFound(plan.Id, dev.Day)
-			log.Println(err)
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindPlanDevotional, Title: plan.Title, Day: dev.Day, Detail: err.Error()})
+			failure = firstError(failure, err)
 			continue
 		}
 
-		err = ts.api.addNextDevotional(AddNextDevotionalReq{PlanId: plan.Id, DevotionalId: dd.Devotional.Id})
-		if err != nil {
-			log.Println(err)
+		if plan.hasDevotional(dd.Devotional.Id) {
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindPlanDevotional, Title: dd.Devotional.Title, Day: dev.Day, Detail: plan.Title})
+			continue
+		}
+		changes = append(changes, feed.Change{Action: feed.ActionAttach, Kind: kindPlanDevotional, Title: dd.Devotional.Title, Day: dev.Day, Detail: plan.Title})
+		if dryRun {
 			continue
 		}
+		err = ts.api.addNextDevotional(ctx, AddNextDevotionalReq{PlanId: plan.Id, DevotionalId: dd.Devotional.Id})
+		failure = firstError(failure, err)
 	}
-	return err
+	return changes, failure
 }
 
-func (ts *TopicSender) addTopicToDevotionals(topic Topic, yearlyDevotionalsJSON string) error {
+// addTopicToDevotionals categorizes the yearly devotionals, it returns the first failure
+func (ts *TopicSender) addTopicToDevotionals(ctx context.Context, topic Topic, yearlyDevotionalsJSON string, dryRun bool) ([]feed.Change, error) {
+
+	yealyDevotionals, err := ts.mapYearlyDevotionalsFromJSON(yearlyDevotionalsJSON)
+	if err != nil {
+		return []feed.Change{{Action: feed.ActionSkip, Kind: kindDevotionalTopic, Title: topic.Title, Detail: err.Error()}}, err
+	}
 
-	yealyDevotionals := ts.mapYearlyDevotionalsFromJSON(yearlyDevotionalsJSON)
-	var err error
+	var changes []feed.Change
+	var failure error
 	for _, dev := range yealyDevotionals {
 		plan := ts.yearlyPlan(GetYearlyPlanReq{Year: dev.Year, AuthorId: ts.to.AuthorId})
 		if plan == nil {
 			err = ErrYearlyPlanNotFound(dev.Year)
-			log.Println(err)
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindDevotionalTopic, Title: topic.Title, Day: dev.Day, Detail: err.Error()})
+			failure = firstError(failure, err)
 			continue
 		}
 
 		dd := ts.dailyDevotional(GetPlanDevotionalReq{TopicId: plan.TopicId, Day: dev.Day})
 		if dd == nil {
 			err = ErrDailyDevotionalNotFound(plan.Id, dev.Day)
-			log.Println(err)
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindDevotionalTopic, Title: topic.Title, Day: dev.Day, Detail: err.Error()})
+			failure = firstError(failure, err)
 			continue
 		}
-		err = ts.api.addDevotionalTopic(AddDevotionalTopicReq{dd.Devotional.Id, topic.Id})
-		if err != nil {
-			log.Print(err)
+
+		if dd.Devotional.hasTopic(topic.Id) {
+			changes = append(changes, feed.Change{Action: feed.ActionSkip, Kind: kindDevotionalTopic, Title: dd.Devotional.Title, Day: dev.Day, Detail: topic.Title})
 			continue
 		}
+		changes = append(changes, feed.Change{Action: feed.ActionAttach, Kind: kindDevotionalTopic, Title: dd.Devotional.Title, Day: dev.Day, Detail: topic.Title})
+		if dryRun {
+			continue
+		}
+		err = ts.api.addDevotionalTopic(ctx, AddDevotionalTopicReq{dd.Devotional.Id, topic.Id})
+		if err == nil {
+			dd.Devotional.Topics = append(dd.Devotional.Topics, topic.Id)
+		}
+		failure = firstError(failure, err)
+	}
+	return changes, failure
+}
+
+func firstError(first, err error) error {
+	if first != nil {
+		return first
 	}
 	return err
 }
@@ -164,18 +245,18 @@ func (ts *TopicSender) yearlyPlan(getPlan GetYearlyPlanReq) *Plan {
 	return nil
 }
 
-func (ts *TopicSender) refreshCache(authorId string) error {
-	if err := ts.refreshPlans(authorId); err != nil {
+func (ts *TopicSender) refreshCache(ctx context.Context, authorId string) error {
+	if err := ts.refreshPlans(ctx, authorId); err != nil {
 		return err
 	}
-	if err := ts.refreshTopics(); err != nil {
+	if err := ts.refreshTopics(ctx); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (ts *TopicSender) refreshPlans(authorId string) error {
-	plans, err := ts.api.getPlans(authorId)
+func (ts *TopicSender) refreshPlans(ctx context.Context, authorId string) error {
+	plans, err := ts.api.getPlans(ctx, authorId)
 	if err != nil {
 		return err
 	}
@@ -187,8 +268,8 @@ func (ts *TopicSender) refreshPlans(authorId string) error {
 	return nil
 }
 
-func (ts *TopicSender) refreshTopics() error {
-	topics, err := ts.api.getTopics()
+func (ts *TopicSender) refreshTopics(ctx context.Context) error {
+	topics, err := ts.api.getTopics(ctx)
 	if err != nil {
 		return err
 	}
@@ -239,11 +320,11 @@ func planTitle(feed feed.Item) string {
 	return txt[0]
 }
 
-func (ts *TopicSender) mapYearlyDevotionalsFromJSON(txtJSON string) []YearlyDevotional {
+func (ts *TopicSender) mapYearlyDevotionalsFromJSON(txtJSON string) ([]YearlyDevotional, error) {
 	var items []YearlyDevotional
 	err := json.Unmarshal([]byte(txtJSON), &items)
 	if err != nil {
-		log.Fatal(err)
+		return nil, ErrInvalidYearlyDevotionals(err)
 	}
-	return items
+	return items, nil
 }
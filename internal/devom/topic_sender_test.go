@@ -0,0 +1,125 @@
+package devom_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTopicSender(srv *devomtest.Server) feed.Sender {
+	sender := devom.NewTopicSender(*devom.NewAPI(srv.URL))
+	sender.Destination(feed.NewDestination("", publisherId, authorId))
+	return sender
+}
+
+func TestTopicSender_Send(t *testing.T) {
+
+	t.Run("it creates the topic and its plan", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedYearlyPlan(2019, authorId, 10)
+		sender := newTopicSender(srv)
+
+		_, err := sender.Send(context.Background(), []feed.Item{
+			{"title": "amor, El", "devotionals": `[{"Year":2019,"Day":3},{"Year":2019,"Day":7}]`},
+		})
+
+		assert.Nil(t, err)
+		topics := srv.Topics()
+		assert.Equal(t, "amor", topics[len(topics)-1].Title)
+		assert.Equal(t, []string{topics[len(topics)-1].Id}, srv.DevotionalTopics("devotional-2019-3"))
+
+		var topicPlan devom.Plan
+		for _, plan := range srv.Plans() {
+			if plan.Title == " El amor" {
+				topicPlan = plan
+			}
+		}
+		assert.Equal(t, map[int]string{1: "devotional-2019-3", 2: "devotional-2019-7"}, srv.DailyDevotionals(topicPlan.Id))
+	})
+
+	t.Run("it reuses the topic plan of a previous import", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedYearlyPlan(2019, authorId, 10)
+		items := []feed.Item{{"title": "amor, El", "devotionals": `[{"Year":2019,"Day":3},{"Year":2019,"Day":7}]`}}
+
+		_, err := newTopicSender(srv).Send(context.Background(), items)
+		assert.Nil(t, err)
+		plans := len(srv.Plans())
+		requests := len(srv.MutatingRequests())
+
+		report, err := newTopicSender(srv).Send(context.Background(), items)
+
+		assert.Nil(t, err)
+		assert.Equal(t, feed.OutcomeSkipped, report.Items[0].Outcome)
+		assert.Equal(t, plans, len(srv.Plans()))
+		assert.Equal(t, requests, len(srv.MutatingRequests()))
+		assert.Equal(t, 1, len(srv.DevotionalTopics("devotional-2019-3")))
+	})
+
+	t.Run("it reports the items with missing devotionals", func(t *testing.T) {
+		srv := devomtest.NewServer()
+		defer srv.Close()
+		srv.SeedYearlyPlan(2019, authorId, 10)
+		sender := newTopicSender(srv)
+
+		report, err := sender.Send(context.Background(), []feed.Item{
+			{"title": "amor, El", "devotionals": `[{"Year":2020,"Day":7},{"Year":2019,"Day":3}]`},
+			{"title": "perdón, El", "devotionals": `[{"Year":2019,"Day":5}]`},
+		})
+
+		assert.Equal(t, feed.ErrCodeRejected, feed.Code(err))
+		assert.Equal(t, feed.OutcomeFailed, report.Items[0].Outcome)
+		assert.Equal(t, devom.ErrYearlyPlanNotFound(2020).Error(), report.Items[0].Error)
+		assert.Equal(t, feed.OutcomeCreated, report.Items[1].Outcome)
+		assert.Empty(t, report.Items[1].Error)
+	})
+}
+
+func TestTopicSender_InvalidDevotionals(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+	srv.SeedYearlyPlan(2019, authorId, 10)
+	sender := newTopicSender(srv)
+
+	_, err := sender.Send(context.Background(), []feed.Item{
+		{"title": "amor, El", "devotionals": `not json`},
+	})
+
+	assert.Equal(t, feed.ErrCodeUnknownFeed, feed.Code(err))
+}
+
+func TestTopicSender_Plan(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+	srv.SeedYearlyPlan(2019, authorId, 10)
+	srv.SeedTopic(devom.Topic{Id: "topic-perdon", Title: "perdón"})
+	sender := newTopicSender(srv)
+
+	report, err := sender.Plan(context.Background(), []feed.Item{
+		{"title": "amor, El", "devotionals": `[{"Year":2019,"Day":3},{"Year":2019,"Day":12}]`},
+		{"title": "perdón, El", "devotionals": `[{"Year":2019,"Day":5}]`},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, feed.OutcomeFailed, report.Items[0].Outcome)
+	assert.Equal(t, devom.ErrDailyDevotionalNotFound("plan-2019", 12).Error(), report.Items[0].Error)
+	assert.Equal(t, []feed.Change{
+		{Action: feed.ActionCreate, Kind: "topic", Title: "amor"},
+		{Action: feed.ActionAttach, Kind: "devotional_topic", Title: "2019 3", Day: 3, Detail: "amor"},
+		{Action: feed.ActionSkip, Kind: "devotional_topic", Title: "amor", Day: 12, Detail: devom.ErrDailyDevotionalNotFound("plan-2019", 12).Error()},
+	}, report.Items[0].Changes)
+	assert.Equal(t, feed.OutcomeSkipped, report.Items[1].Outcome)
+	assert.Equal(t, []feed.Change{
+		{Action: feed.ActionSkip, Kind: "topic", Title: "perdón"},
+		{Action: feed.ActionAttach, Kind: "devotional_topic", Title: "2019 5", Day: 5, Detail: "perdón"},
+		{Action: feed.ActionCreate, Kind: "topic_plan", Title: " El perdón"},
+		{Action: feed.ActionAttach, Kind: "plan_devotional", Title: "2019 5", Day: 5, Detail: " El perdón"},
+	}, report.Items[1].Changes)
+	assert.Empty(t, srv.MutatingRequests())
+}
@@ -0,0 +1,251 @@
+// Package webserver runs the feeder server configured by the environment
+package webserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/checkpoint"
+	"github.com/amelendres/go-feeder/pkg/cloud"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/s3"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/upload"
+	"github.com/amelendres/go-feeder/pkg/web"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/pkg/server"
+)
+
+const (
+	devomAPIUrl  = "http://localhost:8030/api/v1"
+	devomTimeout = "30s"
+	serverPort   = "5500"
+	jobWorkers   = "1"
+	checkpointDB = "checkpoints.db"
+	uploadMax    = "20971520"
+	httpTimeout  = "30s"
+	s3Endpoint   = "https://s3.amazonaws.com"
+	s3Region     = "us-east-1"
+	syncInterval = "1m"
+)
+
+// Run serves every kind of feeds until SIGINT or SIGTERM, with the routes without kind as the defaultLegacyKind ones,
+// unless LEGACY_KIND names another kind
+func Run(defaultLegacyKind string) {
+	var (
+		devomAPIUrl  = getEnv("DEVOM_API_URL", devomAPIUrl)
+		devomToken   = getEnv("DEVOM_API_TOKEN", "")
+		devomTimeout = getEnv("DEVOM_API_TIMEOUT", devomTimeout)
+		serverPort   = getEnv("PORT", serverPort)
+		jobWorkers   = getEnv("JOB_WORKERS", jobWorkers)
+		checkpointDB = getEnv("CHECKPOINT_DB", checkpointDB)
+		uploadMax    = getEnv("UPLOAD_MAX_BYTES", uploadMax)
+		httpTimeout  = getEnv("HTTP_FILE_TIMEOUT", httpTimeout)
+		httpHeaders  = getEnv("HTTP_FILE_HEADERS", "")
+		httpHosts    = getEnv("HTTP_FILE_HOSTS", "")
+		httpPrivate  = getEnv("HTTP_FILE_PRIVATE", "false")
+		uploadDir    = getEnv("UPLOAD_DIR", filepath.Join(os.TempDir(), "feeder-uploads"))
+		syncInterval = getEnv("DRIVE_SYNC_INTERVAL", syncInterval)
+		fieldMap     = getEnv("DEVOTIONAL_FIELD_MAP", "")
+		legacyKind   = getEnv("LEGACY_KIND", defaultLegacyKind)
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	maxSize, err := strconv.ParseInt(uploadMax, 10, 64)
+	if err != nil {
+		log.Fatalf("ERROR: invalid UPLOAD_MAX_BYTES <%s>", uploadMax)
+	}
+	fsp := fs.NewFileProvider(fs.WithMaxSize(maxSize))
+	up := upload.NewFileProvider(uploadDir, maxSize)
+	httpOpts, err := web.HeaderOptions(httpHeaders)
+	if err != nil {
+		log.Fatalf("ERROR: invalid HTTP_FILE_HEADERS: %v", err)
+	}
+	httpOpts = append(httpOpts, web.HostOptions(httpHosts)...)
+	private, err := strconv.ParseBool(httpPrivate)
+	if err != nil {
+		log.Fatalf("ERROR: invalid HTTP_FILE_PRIVATE <%s>", httpPrivate)
+	}
+	if private {
+		httpOpts = append(httpOpts, web.WithPrivateAddresses())
+	}
+	timeout, err := time.ParseDuration(httpTimeout)
+	if err != nil {
+		log.Fatalf("ERROR: invalid HTTP_FILE_TIMEOUT <%s>", httpTimeout)
+	}
+	hp := web.NewFileProvider(append(httpOpts, web.WithTimeout(timeout), web.WithMaxSize(maxSize))...)
+	s3p := s3.NewFileProvider(
+		s3.WithEndpoint(getEnv("S3_ENDPOINT", s3Endpoint)),
+		s3.WithRegion(getEnv("S3_REGION", s3Region)),
+		s3.WithCredentials(os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY"), os.Getenv("S3_SESSION_TOKEN")),
+		s3.WithMaxSize(maxSize),
+	)
+	fileProviders := []feed.FileProvider{fsp, up, hp, s3p}
+	var folders []feed.Folder
+	if _, err := devom.KindOf(legacyKind); err != nil {
+		log.Fatalf("ERROR: invalid LEGACY_KIND: %v", err)
+	}
+	serverOpts := []server.Option{server.WithUploads(up), server.WithLegacyKind(legacyKind)}
+	var driveSync *cloud.DriveSync
+
+	var checkpoints feed.Checkpoints
+	var syncOpts []cloud.SyncOption
+	if checkpointDB != "" {
+		store, err := checkpoint.NewBoltStore(checkpointDB)
+		if err != nil {
+			log.Fatalf("ERROR: unable to open CHECKPOINT_DB <%s>: %v", checkpointDB, err)
+		}
+		defer store.Close()
+		checkpoints = store
+		syncOpts = append(syncOpts, cloud.WithSyncStore(store.Bucket("synced-files")))
+	}
+
+	driveAuth, err := cloud.DriveAuthFromEnv()
+	if err != nil {
+		log.Fatalf("ERROR: %v", err)
+	}
+	if driveAuth.Disabled() {
+		log.Println("Google Drive files are disabled, set GOOGLE_API_KEY or GOOGLE_DRIVE_AUTH")
+	} else {
+		driveService, err := cloud.NewDriveService(ctx, driveAuth)
+		if err != nil {
+			log.Fatalf("ERROR: unable to start the Drive service: %v", err)
+		}
+		fileProviders = append(fileProviders, cloud.NewGDFileProvider(driveService, cloud.WithMaxSize(maxSize)))
+		folders = append(folders, cloud.NewGDFolder(driveService))
+
+		if syncInterval != "" {
+			interval, err := time.ParseDuration(syncInterval)
+			if err != nil {
+				log.Fatalf("ERROR: invalid DRIVE_SYNC_INTERVAL <%s>", syncInterval)
+			}
+			// the Changes API does not accept Api Keys
+			if driveAuth.Method == cloud.AuthAPIKey {
+				syncOpts = append(syncOpts, cloud.WithFilePolling())
+			}
+			driveSync, err = cloud.NewDriveSync(driveService, interval, syncOpts...)
+			if err != nil {
+				log.Fatalf("ERROR: unable to restore the synced files: %v", err)
+			}
+			serverOpts = append(serverOpts, server.WithSync(driveSync))
+		}
+	}
+
+	timeout, err = time.ParseDuration(devomTimeout)
+	if err != nil {
+		log.Fatalf("ERROR: invalid DEVOM_API_TIMEOUT <%s>", devomTimeout)
+	}
+	devomOpts := []devom.Option{devom.WithTimeout(timeout)}
+	if devomToken != "" {
+		devomOpts = append(devomOpts, devom.WithBearerToken(devomToken))
+	}
+	api := *devom.NewAPI(devomAPIUrl, devomOpts...)
+
+	workers, err := strconv.Atoi(jobWorkers)
+	if err != nil {
+		log.Fatalf("ERROR: invalid JOB_WORKERS <%s>", jobWorkers)
+	}
+	k := kinds{ctx: ctx, providers: fileProviders, folders: folders, checkpoints: checkpoints, store: jobs.NewMemoryStore(), workers: workers}
+
+	registry := server.NewRegistry()
+	// the mapping of the requests without fieldMap
+	mapping, err := devom.ParseFieldMapping(fieldMap)
+	if err != nil {
+		log.Fatalf("ERROR: invalid DEVOTIONAL_FIELD_MAP: %v", err)
+	}
+	for _, kind := range devom.Kinds {
+		kind := kind
+		registry.Register(kind.Name, k.feeds(
+			func() feed.Parser { return kind.NewParser(api, mapping) },
+			func() feed.Sender { return kind.NewSender(api) },
+		))
+	}
+
+	ds := server.NewFeederServer(registry, serverOpts...)
+	if driveSync != nil {
+		go driveSync.Run(ctx)
+	}
+
+	srv := &http.Server{
+		Addr:        fmt.Sprintf(":%s", serverPort),
+		Handler:     ds,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go shutdown(srv, cancel)
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("could not listen on port %s %v", serverPort, err)
+	}
+}
+
+// kinds builds the services of each kind of feeds, sharing the file providers, checkpoints and jobs
+type kinds struct {
+	ctx         context.Context
+	providers   []feed.FileProvider
+	folders     []feed.Folder
+	checkpoints feed.Checkpoints
+	store       jobs.Store
+	workers     int
+}
+
+// feeds builds a parser and a sender for each request, job and folder import, as they keep its destination
+func (k kinds) feeds(newParser func() feed.Parser, newSender func() feed.Sender) server.Feeds {
+	newFeeding := func() feeding.Service {
+		return feeding.NewService(feed.NewFeeder(newParser(), k.providers))
+	}
+	newSending := func() sending.Service {
+		return k.sending(feed.NewFeeder(newParser(), k.providers), newSender())
+	}
+
+	folders := batch.NewService(newSending, k.folders)
+
+	return server.Feeds{
+		Sender:  newSending,
+		Feeder:  newFeeding,
+		Jobs:    jobs.NewService(k.ctx, k.store, newFeeding, newSending, k.workers, jobs.WithFolders(folders)),
+		Folders: folders,
+	}
+}
+
+func (k kinds) sending(f feed.Feeder, s feed.Sender) sending.Service {
+	if k.checkpoints != nil {
+		return sending.NewResumableService(s, f, k.checkpoints)
+	}
+	return sending.NewService(s, f)
+}
+
+// shutdown stops the server and cancels the running imports on SIGINT or SIGTERM
+func shutdown(srv *http.Server, cancel context.CancelFunc) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	<-sigs
+
+	cancel()
+	if err := srv.Shutdown(context.Background()); err != nil {
+		log.Printf("could not shutdown the server %v", err)
+	}
+}
+
+// TODO: refactor as an env package
+func getEnv(key, fallback string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		value = fallback
+	}
+	return value
+}
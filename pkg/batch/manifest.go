@@ -0,0 +1,145 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/sending"
+)
+
+// ManifestName is the file of a folder mapping its documents to their destinations
+const ManifestName = "feeder.json"
+
+// planIdName matches the document names starting with the plan id, as "<planId> Devotionals 2021.docx"
+var planIdName = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+var ErrInvalidManifest = func(err error) error {
+	return feed.NewError(feed.ErrCodeInvalidRequest, fmt.Errorf("invalid %s: %w", ManifestName, err))
+}
+
+// Manifest maps the documents of a folder to their destinations,
+// the destination fields of a document default to the manifest ones
+type Manifest struct {
+	AuthorId    string      `json:"authorId"`
+	PublisherId string      `json:"publisherId"`
+	Upsert      bool        `json:"upsert"`
+	Format      feed.Format `json:"format"`
+	Documents   []Document  `json:"documents"`
+}
+
+type Document struct {
+	File        string      `json:"file"`
+	PlanId      string      `json:"planId"`
+	AuthorId    string      `json:"authorId"`
+	PublisherId string      `json:"publisherId"`
+	Upsert      *bool       `json:"upsert"`
+	Format      feed.Format `json:"format"`
+}
+
+// manifest reads the folder manifest, it is nil when the folder has none
+func manifest(ctx context.Context, folder feed.Folder, files []feed.FolderFile) (*Manifest, error) {
+	for _, f := range files {
+		if f.Name != ManifestName {
+			continue
+		}
+		r, err := folder.File(ctx, f.Url)
+		if err != nil {
+			return nil, err
+		}
+		var m Manifest
+		if err := json.NewDecoder(r).Decode(&m); err != nil {
+			return nil, ErrInvalidManifest(err)
+		}
+		return &m, nil
+	}
+	return nil, nil
+}
+
+// document is a file of the folder with the request sending it,
+// the file url is empty when the manifest lists a file missing in the folder
+type document struct {
+	name string
+	req  sending.SendReq
+}
+
+// documents maps the folder files to their destinations, with the manifest or by the
+// plan id their names start with, falling back to the request plan, and returns the names of the unmapped files
+func documents(req Req, m *Manifest, files []feed.FolderFile) ([]document, []string) {
+	if m != nil {
+		return m.documents(req, files)
+	}
+
+	docs := []document{}
+	ignored := []string{}
+	for _, f := range files {
+		planId := first(PlanId(f.Name), req.PlanId)
+		if planId == "" {
+			ignored = append(ignored, f.Name)
+			continue
+		}
+		docs = append(docs, document{f.Name, sending.SendReq{
+			PlanId:      planId,
+			AuthorId:    req.AuthorId,
+			PublisherId: req.PublisherId,
+			FileUrl:     f.Url,
+			Upsert:      req.Upsert,
+			Format:      req.Format,
+			FieldMap:    req.FieldMap,
+		}})
+	}
+	return docs, ignored
+}
+
+func (m *Manifest) documents(req Req, files []feed.FolderFile) ([]document, []string) {
+	urls := make(map[string]string, len(files))
+	for _, f := range files {
+		urls[f.Name] = f.Url
+	}
+
+	docs := []document{}
+	mapped := map[string]bool{ManifestName: true}
+	for _, d := range m.Documents {
+		mapped[d.File] = true
+		docs = append(docs, document{d.File, sending.SendReq{
+			PlanId:      first(d.PlanId, req.PlanId),
+			AuthorId:    first(d.AuthorId, m.AuthorId, req.AuthorId),
+			PublisherId: first(d.PublisherId, m.PublisherId, req.PublisherId),
+			FileUrl:     urls[d.File],
+			Upsert:      d.upsert(m.Upsert || req.Upsert),
+			Format:      feed.Format(first(string(d.Format), string(m.Format), string(req.Format))),
+			FieldMap:    req.FieldMap,
+		}})
+	}
+
+	ignored := []string{}
+	for _, f := range files {
+		if !mapped[f.Name] {
+			ignored = append(ignored, f.Name)
+		}
+	}
+	return docs, ignored
+}
+
+// PlanId is the plan id the document name starts with, if any
+func PlanId(name string) string {
+	return planIdName.FindString(name)
+}
+
+func (d Document) upsert(fallback bool) bool {
+	if d.Upsert == nil {
+		return fallback
+	}
+	return *d.Upsert
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/sending"
+)
+
+var (
+	ErrUnknownFolder   = feed.NewError(feed.ErrCodeInvalidFileUrl, errors.New("unknown folder"))
+	ErrMissingDocument = func(name string) error {
+		return feed.NewError(feed.ErrCodeFileNotFound, fmt.Errorf("document <%s> is not in the folder", name))
+	}
+)
+
+// Req imports the documents of a folder, the destination fields are the defaults of every document,
+// with the plan of the ones not named by their plan
+type Req struct {
+	FolderUrl, PlanId, AuthorId, PublisherId string
+	Upsert                                   bool
+	Format                                   feed.Format
+	FieldMap                                 string
+}
+
+// DocumentReport is the report of importing a document of the folder, or its failure
+type DocumentReport struct {
+	File         string             `json:"file"`
+	FileUrl      string             `json:"fileUrl"`
+	PlanId       string             `json:"planId"`
+	Report       *feed.Report       `json:"report,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Code         feed.ErrorCode     `json:"code,omitempty"`
+	UnknownItems []feed.UnknownItem `json:"unknownItems,omitempty"`
+}
+
+// Report aggregates the reports of the folder documents, with the item outcomes counted by outcome
+type Report struct {
+	Documents []DocumentReport     `json:"documents"`
+	Ignored   []string             `json:"ignored"`
+	Outcomes  map[feed.Outcome]int `json:"outcomes"`
+	Failed    int                  `json:"failed"`
+}
+
+type Service interface {
+	// Send imports every document of the folder, going on after a failing one
+	Send(ctx context.Context, req Req) (*Report, error)
+	// Plan reports what Send would do, as a dry-run
+	Plan(ctx context.Context, req Req) (*Report, error)
+}
+
+type service struct {
+	folders   map[string]feed.Folder
+	newSender sending.Factory
+}
+
+// NewService imports the documents of the folders one at a time, with a sender of its own for each import
+func NewService(s sending.Factory, folders []feed.Folder) Service {
+	bs := &service{newSender: s, folders: make(map[string]feed.Folder)}
+	for _, f := range folders {
+		bs.folders[f.Name()] = f
+	}
+	return bs
+}
+
+func (bs *service) Send(ctx context.Context, req Req) (*Report, error) {
+	return bs.run(ctx, req, bs.newSender().Send)
+}
+
+func (bs *service) Plan(ctx context.Context, req Req) (*Report, error) {
+	return bs.run(ctx, req, bs.newSender().Plan)
+}
+
+func (bs *service) run(ctx context.Context, req Req, send func(context.Context, sending.SendReq) (*feed.Report, error)) (*Report, error) {
+	folder, ok := bs.folders[feed.ProviderName(req.FolderUrl)]
+	if !ok {
+		return nil, ErrUnknownFolder
+	}
+	files, err := folder.Files(ctx, req.FolderUrl)
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifest(ctx, folder, files)
+	if err != nil {
+		return nil, err
+	}
+	docs, ignored := documents(req, m, files)
+
+	report := &Report{Documents: []DocumentReport{}, Ignored: ignored, Outcomes: map[feed.Outcome]int{}}
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		dr, err := sendDocument(ctx, doc, send)
+		report.add(doc, dr, err)
+	}
+	return report, nil
+}
+
+func sendDocument(ctx context.Context, doc document, send func(context.Context, sending.SendReq) (*feed.Report, error)) (*feed.Report, error) {
+	if doc.req.FileUrl == "" {
+		return nil, ErrMissingDocument(doc.name)
+	}
+	return send(ctx, doc.req)
+}
+
+func (r *Report) add(doc document, report *feed.Report, err error) {
+	dr := DocumentReport{File: doc.name, FileUrl: doc.req.FileUrl, PlanId: doc.req.PlanId, Report: report}
+	if report != nil {
+		for _, item := range report.Items {
+			r.Outcomes[item.Outcome]++
+		}
+	}
+	if err != nil {
+		dr.Error = err.Error()
+		dr.Code = feed.Code(err)
+		var unknown *sending.UnknownFeedError
+		if errors.As(err, &unknown) {
+			dr.UnknownItems = unknown.UnknownItems
+		}
+		r.Failed++
+	}
+	r.Documents = append(r.Documents, dr)
+}
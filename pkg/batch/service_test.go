@@ -0,0 +1,143 @@
+package batch_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	planA = "23a63256-f264-4d94-b7ed-8ce60f744ae3"
+	planB = "9158becf-6f89-4366-9541-ae5b99689cc2"
+)
+
+// stubFolder serves its files content by name on the stub:// scheme
+type stubFolder map[string]string
+
+func (sf stubFolder) Files(ctx context.Context, url string) ([]feed.FolderFile, error) {
+	files := []feed.FolderFile{}
+	for name := range sf {
+		files = append(files, feed.FolderFile{Name: name, Url: "stub://" + name})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func (sf stubFolder) File(ctx context.Context, url string) (io.Reader, error) {
+	return strings.NewReader(sf[strings.TrimPrefix(url, "stub://")]), nil
+}
+
+func (sf stubFolder) Name() string {
+	return "stub"
+}
+
+// stubSender creates an item of each document, failing the ones with unknown items
+type stubSender struct {
+	reqs []sending.SendReq
+}
+
+func (ss *stubSender) Send(ctx context.Context, req sending.SendReq) (*feed.Report, error) {
+	ss.reqs = append(ss.reqs, req)
+	if strings.Contains(req.FileUrl, "unknown") {
+		return nil, &sending.UnknownFeedError{UnknownItems: []feed.UnknownItem{{Item: []string{"?"}}}}
+	}
+	report := feed.NewReport()
+	report.Add(feed.Item{"file": req.FileUrl}, feed.OutcomeCreated, nil, nil)
+	return report, nil
+}
+
+func (ss *stubSender) SendItems(ctx context.Context, req sending.SendReq, feeds *feed.ParsedItems) (*feed.Report, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (ss *stubSender) Plan(ctx context.Context, req sending.SendReq) (*feed.Report, error) {
+	return ss.Send(ctx, req)
+}
+
+func TestService_Send(t *testing.T) {
+	req := batch.Req{FolderUrl: "stub://folder", AuthorId: "author", PublisherId: "publisher"}
+
+	t.Run("it imports the documents named by their plan", func(t *testing.T) {
+		ss := &stubSender{}
+		bs := batch.NewService(sending.Shared(ss), []feed.Folder{stubFolder{
+			planA + " Devotionals.docx": "",
+			"cover.png":                 "",
+		}})
+
+		report, err := bs.Send(context.Background(), req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []sending.SendReq{{
+			PlanId: planA, AuthorId: "author", PublisherId: "publisher", FileUrl: "stub://" + planA + " Devotionals.docx",
+		}}, ss.reqs)
+		assert.Equal(t, []string{"cover.png"}, report.Ignored)
+		assert.Equal(t, 1, report.Outcomes[feed.OutcomeCreated])
+	})
+
+	t.Run("it imports the other documents to the request plan", func(t *testing.T) {
+		ss := &stubSender{}
+		bs := batch.NewService(sending.Shared(ss), []feed.Folder{stubFolder{
+			planA + " 2021.docx": "",
+			"2022.docx":          "",
+		}})
+
+		report, err := bs.Send(context.Background(), batch.Req{FolderUrl: "stub://folder", PlanId: planB})
+
+		assert.Nil(t, err)
+		assert.Empty(t, report.Ignored)
+		assert.Equal(t, planB, ss.reqs[0].PlanId)
+		assert.Equal(t, planA, ss.reqs[1].PlanId)
+	})
+
+	t.Run("it maps the documents with the manifest", func(t *testing.T) {
+		ss := &stubSender{}
+		bs := batch.NewService(sending.Shared(ss), []feed.Folder{stubFolder{
+			batch.ManifestName: `{"publisherId": "manifest", "upsert": true, "documents": [
+				{"file": "2021.docx", "planId": "` + planA + `", "upsert": false},
+				{"file": "unknown.docx", "planId": "` + planB + `", "authorId": "guest"},
+				{"file": "missing.docx", "planId": "` + planB + `"}
+			]}`,
+			"2021.docx":    "",
+			"unknown.docx": "",
+			"notes.txt":    "",
+		}})
+
+		report, err := bs.Send(context.Background(), req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []sending.SendReq{
+			{PlanId: planA, AuthorId: "author", PublisherId: "manifest", FileUrl: "stub://2021.docx"},
+			{PlanId: planB, AuthorId: "guest", PublisherId: "manifest", FileUrl: "stub://unknown.docx", Upsert: true},
+		}, ss.reqs)
+		assert.Equal(t, []string{"notes.txt"}, report.Ignored)
+		assert.Equal(t, 2, report.Failed)
+		assert.Equal(t, feed.ErrCodeUnknownFeed, report.Documents[1].Code)
+		assert.Len(t, report.Documents[1].UnknownItems, 1)
+		assert.Equal(t, feed.ErrCodeFileNotFound, report.Documents[2].Code)
+		assert.Equal(t, 1, report.Outcomes[feed.OutcomeCreated])
+	})
+
+	t.Run("it fails with an invalid manifest", func(t *testing.T) {
+		bs := batch.NewService(sending.Shared(&stubSender{}), []feed.Folder{stubFolder{batch.ManifestName: "{"}})
+
+		_, err := bs.Send(context.Background(), req)
+
+		assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+	})
+
+	t.Run("it fails with an unknown folder", func(t *testing.T) {
+		bs := batch.NewService(sending.Shared(&stubSender{}), nil)
+
+		_, err := bs.Send(context.Background(), batch.Req{FolderUrl: "gdrive://folder"})
+
+		assert.Equal(t, batch.ErrUnknownFolder, err)
+	})
+}
@@ -0,0 +1,22 @@
+package feed
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionAttach Action = "attach"
+	ActionSkip   Action = "skip"
+	// on upsert, the existing items are updated or left unchanged
+	ActionUpdate    Action = "update"
+	ActionUnchanged Action = "unchanged"
+)
+
+// Change describes an operation a Sender does, or would do in a dry-run,
+// against the destination
+type Change struct {
+	Action Action `json:"action"`
+	Kind   string `json:"kind"`
+	Title  string `json:"title"`
+	Day    int    `json:"day,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
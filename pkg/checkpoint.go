@@ -0,0 +1,27 @@
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// Checkpoints keeps the items each import already sent, so a retry resumes after them.
+// Implementations must be safe for concurrent use
+type Checkpoints interface {
+	Sent(importKey, itemKey string) (bool, error)
+	Save(importKey string, itemKeys ...string) error
+}
+
+// ImportKey identifies the import of a file, by its checksum, to a destination
+func ImportKey(checksum string, d *Destination) string {
+	return fmt.Sprintf("%s:%s:%s:%s:%t", checksum, d.PlanId, d.PublisherId, d.AuthorId, d.Upsert)
+}
+
+// ItemKey identifies an item by its content
+func ItemKey(item Item) string {
+	b, _ := json.Marshal(item)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
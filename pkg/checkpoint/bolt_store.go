@@ -0,0 +1,99 @@
+package checkpoint
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore keeps the feed checkpoints in a BoltDB file, a bucket by import
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (bs *BoltStore) Sent(importKey, itemKey string) (bool, error) {
+	sent := false
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(importKey)); b != nil {
+			sent = b.Get([]byte(itemKey)) != nil
+		}
+		return nil
+	})
+	return sent, err
+}
+
+func (bs *BoltStore) Save(importKey string, itemKeys ...string) error {
+	if len(itemKeys) == 0 {
+		return nil
+	}
+	sentAt := []byte(time.Now().UTC().Format(time.RFC3339))
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(importKey))
+		if err != nil {
+			return err
+		}
+		for _, key := range itemKeys {
+			if err := b.Put([]byte(key), sentAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (bs *BoltStore) Close() error {
+	return bs.db.Close()
+}
+
+// Bucket keeps values by key in a bucket of the same file, apart from the checkpoints
+func (bs *BoltStore) Bucket(name string) *BoltBucket {
+	return &BoltBucket{db: bs.db, name: []byte(name)}
+}
+
+// BoltBucket is a named bucket of a BoltStore
+type BoltBucket struct {
+	db   *bolt.DB
+	name []byte
+}
+
+func (bb *BoltBucket) Put(key string, value []byte) error {
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bb.name)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), value)
+	})
+}
+
+func (bb *BoltBucket) Delete(key string) error {
+	return bb.db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bb.name); b != nil {
+			return b.Delete([]byte(key))
+		}
+		return nil
+	})
+}
+
+func (bb *BoltBucket) All() (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	err := bb.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bb.name)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			values[string(k)] = append([]byte(nil), v...)
+			return nil
+		})
+	})
+	return values, err
+}
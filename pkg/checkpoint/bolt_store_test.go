@@ -0,0 +1,72 @@
+package checkpoint_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/amelendres/go-feeder/pkg/checkpoint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.db")
+
+	t.Run("it keeps the sent items by import", func(t *testing.T) {
+		store, err := checkpoint.NewBoltStore(path)
+		assert.Nil(t, err)
+		defer store.Close()
+
+		assert.Nil(t, store.Save("import-1", "item-1", "item-2"))
+
+		sent, err := store.Sent("import-1", "item-2")
+		assert.Nil(t, err)
+		assert.True(t, sent)
+		sent, _ = store.Sent("import-1", "item-3")
+		assert.False(t, sent)
+		sent, _ = store.Sent("import-2", "item-1")
+		assert.False(t, sent)
+	})
+
+	t.Run("it persists the checkpoints", func(t *testing.T) {
+		store, err := checkpoint.NewBoltStore(path)
+		assert.Nil(t, err)
+		defer store.Close()
+
+		sent, err := store.Sent("import-1", "item-1")
+		assert.Nil(t, err)
+		assert.True(t, sent)
+	})
+}
+
+func TestBoltStore_Bucket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.db")
+
+	t.Run("it keeps the values by key", func(t *testing.T) {
+		store, err := checkpoint.NewBoltStore(path)
+		assert.Nil(t, err)
+		defer store.Close()
+		bucket := store.Bucket("synced-files")
+
+		assert.Nil(t, bucket.Put("key-1", []byte("value-1")))
+		assert.Nil(t, bucket.Put("key-2", []byte("value-2")))
+		assert.Nil(t, bucket.Delete("key-2"))
+		assert.Nil(t, bucket.Delete("key-3"))
+
+		values, err := bucket.All()
+		assert.Nil(t, err)
+		assert.Equal(t, map[string][]byte{"key-1": []byte("value-1")}, values)
+	})
+
+	t.Run("it persists the values", func(t *testing.T) {
+		store, err := checkpoint.NewBoltStore(path)
+		assert.Nil(t, err)
+		defer store.Close()
+
+		values, err := store.Bucket("synced-files").All()
+		assert.Nil(t, err)
+		assert.Len(t, values, 1)
+		values, err = store.Bucket("other").All()
+		assert.Nil(t, err)
+		assert.Empty(t, values)
+	})
+}
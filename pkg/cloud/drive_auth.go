@@ -0,0 +1,105 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// Drive authentication methods
+const (
+	AuthAPIKey         = "api_key"
+	AuthServiceAccount = "service_account"
+	AuthOAuth          = "oauth"
+)
+
+var ErrInvalidDriveAuth = func(err error) error {
+	return fmt.Errorf("invalid Google Drive authentication: %w", err)
+}
+
+// DriveAuth configures the Drive client authentication, an API key only reads the link-shared files,
+// a service account or an OAuth user read the private files shared with them
+type DriveAuth struct {
+	Method string
+	APIKey string
+	// Credentials is the JSON key of the service account
+	Credentials []byte
+	// ClientId, ClientSecret and RefreshToken of the OAuth user
+	ClientId, ClientSecret, RefreshToken string
+	// TokenURL exchanges the OAuth refresh token, the Google one by default
+	TokenURL string
+}
+
+// DriveAuthFromEnv reads the auth method of GOOGLE_DRIVE_AUTH, api_key by default, with its
+// GOOGLE_API_KEY, the GOOGLE_APPLICATION_CREDENTIALS service account key file or the
+// GOOGLE_OAUTH_CLIENT_ID, GOOGLE_OAUTH_CLIENT_SECRET and GOOGLE_OAUTH_REFRESH_TOKEN
+func DriveAuthFromEnv() (DriveAuth, error) {
+	auth := DriveAuth{
+		Method:       os.Getenv("GOOGLE_DRIVE_AUTH"),
+		APIKey:       os.Getenv("GOOGLE_API_KEY"),
+		ClientId:     os.Getenv("GOOGLE_OAUTH_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_OAUTH_CLIENT_SECRET"),
+		RefreshToken: os.Getenv("GOOGLE_OAUTH_REFRESH_TOKEN"),
+	}
+	if auth.Method == "" {
+		auth.Method = AuthAPIKey
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); auth.Method == AuthServiceAccount && path != "" {
+		credentials, err := ioutil.ReadFile(path)
+		if err != nil {
+			return auth, ErrInvalidDriveAuth(err)
+		}
+		auth.Credentials = credentials
+	}
+	return auth, nil
+}
+
+// Disabled tells whether no Drive authentication is configured
+func (auth DriveAuth) Disabled() bool {
+	return (auth.Method == AuthAPIKey || auth.Method == "") && auth.APIKey == ""
+}
+
+// NewDriveService builds a read-only Drive client authenticated by the auth method
+func NewDriveService(ctx context.Context, auth DriveAuth, opts ...option.ClientOption) (*drive.Service, error) {
+	switch auth.Method {
+	case AuthAPIKey, "":
+		if auth.APIKey == "" {
+			return nil, ErrInvalidDriveAuth(errors.New("missing the API key"))
+		}
+		opts = append(opts, option.WithAPIKey(auth.APIKey))
+	case AuthServiceAccount:
+		if len(auth.Credentials) == 0 {
+			return nil, ErrInvalidDriveAuth(errors.New("missing the service account credentials"))
+		}
+		opts = append(opts, option.WithCredentialsJSON(auth.Credentials), option.WithScopes(drive.DriveReadonlyScope))
+	case AuthOAuth:
+		if auth.ClientId == "" || auth.ClientSecret == "" || auth.RefreshToken == "" {
+			return nil, ErrInvalidDriveAuth(errors.New("missing the OAuth client or refresh token"))
+		}
+		conf := &oauth2.Config{
+			ClientID:     auth.ClientId,
+			ClientSecret: auth.ClientSecret,
+			Endpoint:     google.Endpoint,
+			Scopes:       []string{drive.DriveReadonlyScope},
+		}
+		if auth.TokenURL != "" {
+			conf.Endpoint.TokenURL = auth.TokenURL
+		}
+		opts = append(opts, option.WithTokenSource(conf.TokenSource(ctx, &oauth2.Token{RefreshToken: auth.RefreshToken})))
+	default:
+		return nil, ErrInvalidDriveAuth(fmt.Errorf("unknown method <%s>", auth.Method))
+	}
+
+	ds, err := drive.NewService(ctx, opts...)
+	if err != nil {
+		return nil, ErrInvalidDriveAuth(err)
+	}
+	return ds, nil
+}
@@ -0,0 +1,143 @@
+package cloud_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/amelendres/go-feeder/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+const fileId = "1XI0cxe6T1VSipeeCmEbk14VDkZM5PS_c"
+
+// newDrive stubs the Google token and Drive endpoints, serving the file to the service account
+// and to the OAuth user refreshing its token
+func newDrive(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		token := "service-account-token"
+		if r.FormValue("grant_type") == "refresh_token" {
+			// the Google endpoint sends the client in the params
+			if r.FormValue("refresh_token") != "refresh-token" || r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" {
+				w.Header().Set("content-type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant"}`)
+				return
+			}
+			token = "user-token"
+		}
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"%s","token_type":"Bearer","expires_in":3600}`, token)
+	})
+	mux.HandleFunc("/files/"+fileId, func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer service-account-token" && auth != "Bearer user-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("supportsAllDrives") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("alt") != "media" {
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprint(w, `{"mimeType":"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}`)
+			return
+		}
+		fmt.Fprint(w, "private document")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func serviceAccountJSON(t *testing.T, tokenURL string) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	b, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "feeder",
+		"private_key_id": "key-id",
+		"private_key":    string(pemKey),
+		"client_email":   "feeder@feeder.iam.gserviceaccount.com",
+		"client_id":      "123",
+		"token_uri":      tokenURL,
+	})
+	return b
+}
+
+func TestNewDriveService(t *testing.T) {
+	srv := newDrive(t)
+
+	t.Run("it reads private files as a service account", func(t *testing.T) {
+		ds, err := cloud.NewDriveService(context.Background(), cloud.DriveAuth{
+			Method:      cloud.AuthServiceAccount,
+			Credentials: serviceAccountJSON(t, srv.URL+"/token"),
+		}, option.WithEndpoint(srv.URL+"/"))
+		assert.Nil(t, err)
+
+		r, err := cloud.NewGDFileProvider(ds).File(context.Background(), "gdrive://"+fileId)
+
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "private document", string(b))
+	})
+
+	t.Run("it reads private files as the OAuth user of the refresh token", func(t *testing.T) {
+		ds, err := cloud.NewDriveService(context.Background(), cloud.DriveAuth{
+			Method:       cloud.AuthOAuth,
+			ClientId:     "client",
+			ClientSecret: "secret",
+			RefreshToken: "refresh-token",
+			TokenURL:     srv.URL + "/token",
+		}, option.WithEndpoint(srv.URL+"/"))
+		assert.Nil(t, err)
+
+		r, err := cloud.NewGDFileProvider(ds).File(context.Background(), "gdrive://"+fileId)
+
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "private document", string(b))
+	})
+
+	t.Run("it fails with a revoked refresh token", func(t *testing.T) {
+		ds, err := cloud.NewDriveService(context.Background(), cloud.DriveAuth{
+			Method:       cloud.AuthOAuth,
+			ClientId:     "client",
+			ClientSecret: "secret",
+			RefreshToken: "revoked-token",
+			TokenURL:     srv.URL + "/token",
+		}, option.WithEndpoint(srv.URL+"/"))
+		assert.Nil(t, err)
+
+		_, err = cloud.NewGDFileProvider(ds).File(context.Background(), "gdrive://"+fileId)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("it fails without the method settings", func(t *testing.T) {
+		for _, auth := range []cloud.DriveAuth{
+			{Method: cloud.AuthAPIKey},
+			{Method: cloud.AuthServiceAccount},
+			{Method: cloud.AuthOAuth, ClientId: "client"},
+			{Method: "password"},
+		} {
+			_, err := cloud.NewDriveService(context.Background(), auth)
+
+			assert.NotNil(t, err, auth.Method)
+		}
+	})
+}
@@ -0,0 +1,357 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/sending"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const defaultSyncInterval = time.Minute
+
+var (
+	ErrNotGoogleDriveFile = feed.NewError(feed.ErrCodeInvalidFileUrl, errors.New("only Google Drive files can be synced"))
+	ErrSyncNotHandled     = feed.NewError(feed.ErrCodeInternal, errors.New("no import of the changed files"))
+)
+
+// SyncStore keeps the synced files by key, so they are still synced after a restart
+type SyncStore interface {
+	Put(key string, value []byte) error
+	Delete(key string) error
+	All() (map[string][]byte, error)
+}
+
+// DriveSync polls the Drive changes, importing the registered files of each kind whose content changed
+type DriveSync struct {
+	drive     *drive.Service
+	interval  time.Duration
+	store     SyncStore
+	pollFiles bool
+
+	// polling serializes the polls, mu guards the files without being held during the Drive calls
+	polling   sync.Mutex
+	mu        sync.Mutex
+	files     map[string]*syncedFile
+	onChange  func(ctx context.Context, kind string, req sending.SendReq) error
+	pageToken string
+}
+
+// syncedFile is a file registered for a kind, with the content version of its last import
+type syncedFile struct {
+	Kind    string          `json:"kind"`
+	Req     sending.SendReq `json:"req"`
+	FileId  string          `json:"fileId"`
+	Version version         `json:"version"`
+	// Pending is the changed version whose import has not succeeded yet, retried on each poll
+	Pending *version `json:"pending,omitempty"`
+}
+
+// mark sets the version as pending unless it is the imported one, telling whether the pending version changed
+func (f *syncedFile) mark(v version) bool {
+	var pending, next version
+	if f.Pending != nil {
+		pending = *f.Pending
+	}
+	if v != f.Version {
+		next = v
+	}
+	if next == pending {
+		return false
+	}
+	f.Pending = nil
+	if next != (version{}) {
+		f.Pending = &next
+	}
+	return true
+}
+
+type version struct {
+	ModifiedTime string `json:"modifiedTime"`
+	Md5Checksum  string `json:"md5Checksum"`
+}
+
+type SyncOption func(*DriveSync)
+
+// WithSyncStore keeps the synced files in the store
+func WithSyncStore(store SyncStore) SyncOption {
+	return func(s *DriveSync) {
+		s.store = store
+	}
+}
+
+// WithFilePolling gets the version of every synced file on each poll instead of listing the changes,
+// for the Api Keys, which the Changes API does not accept
+func WithFilePolling() SyncOption {
+	return func(s *DriveSync) {
+		s.pollFiles = true
+	}
+}
+
+// NewDriveSync polls the changes every interval, restoring the files synced in the store
+func NewDriveSync(ds *drive.Service, interval time.Duration, opts ...SyncOption) (*DriveSync, error) {
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	s := &DriveSync{drive: ds, interval: interval, files: make(map[string]*syncedFile)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.store == nil {
+		return s, nil
+	}
+
+	stored, err := s.store.All()
+	if err != nil {
+		return nil, err
+	}
+	for key, b := range stored {
+		f := &syncedFile{}
+		if err := json.Unmarshal(b, f); err != nil {
+			return nil, err
+		}
+		s.files[key] = f
+	}
+	return s, nil
+}
+
+// OnChange sets the import of the changed files, a file keeps changed until its import succeeds
+func (s *DriveSync) OnChange(onChange func(ctx context.Context, kind string, req sending.SendReq) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = onChange
+}
+
+// Register imports the file of the request for the kind whenever its content changes,
+// replacing its previous request for the same kind
+func (s *DriveSync) Register(ctx context.Context, kind string, req sending.SendReq) error {
+	fp := &GDFileProvider{drive: s.drive}
+	if feed.ProviderName(req.FileUrl) != fp.Name() {
+		return ErrNotGoogleDriveFile
+	}
+	fileId, err := fp.fileId(req.FileUrl)
+	if err != nil {
+		return err
+	}
+	f, err := s.drive.Files.Get(fileId).SupportsAllDrives(true).Fields("modifiedTime, md5Checksum").Context(ctx).Do()
+	if err != nil {
+		return driveError(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sf := &syncedFile{Kind: kind, Req: req, FileId: fileId, Version: version{f.ModifiedTime, f.Md5Checksum}}
+	if err := s.save(sf); err != nil {
+		return err
+	}
+	s.files[syncKey(kind, fileId)] = sf
+	return nil
+}
+
+// Unregister stops syncing the file for the kind
+func (s *DriveSync) Unregister(kind, fileUrl string) error {
+	fp := &GDFileProvider{drive: s.drive}
+	fileId, err := fp.fileId(fileUrl)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := syncKey(kind, fileId)
+	if s.store != nil {
+		if err := s.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	delete(s.files, key)
+	return nil
+}
+
+// Run polls the changes until ctx is done
+func (s *DriveSync) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("polling Drive changes: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll imports the registered files changed since the previous poll, or whose import failed,
+// and returns the urls of the imported ones
+func (s *DriveSync) Poll(ctx context.Context) ([]string, error) {
+	changed, err := s.changes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	for _, f := range changed {
+		if err := s.sync(ctx, f); err != nil {
+			log.Printf("syncing %s of %s: %v", f.Req.FileUrl, f.Kind, err)
+			continue
+		}
+		urls = append(urls, f.Req.FileUrl)
+	}
+	return urls, nil
+}
+
+// sync imports the changed file, recording its version once imported
+func (s *DriveSync) sync(ctx context.Context, f syncedFile) error {
+	s.mu.Lock()
+	onChange := s.onChange
+	s.mu.Unlock()
+	if onChange == nil {
+		return ErrSyncNotHandled
+	}
+	if err := onChange(ctx, f.Kind, f.Req); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.files[syncKey(f.Kind, f.FileId)]
+	// unregistered, registered again or changed again while importing
+	if !ok || current.Pending == nil || *current.Pending != *f.Pending {
+		return nil
+	}
+	current.Version, current.Pending = *f.Pending, nil
+	return s.save(current)
+}
+
+// changes marks the registered files whose content changed as pending and lists every pending file,
+// the page token only moves forward once every page is listed
+func (s *DriveSync) changes(ctx context.Context) ([]syncedFile, error) {
+	s.polling.Lock()
+	defer s.polling.Unlock()
+	s.mu.Lock()
+	ids, token := s.fileIds(), s.pageToken
+	s.mu.Unlock()
+
+	var versions map[string]version
+	switch {
+	case s.pollFiles:
+		versions = s.fileVersions(ctx, ids)
+	case token == "":
+		start, err := s.drive.Changes.GetStartPageToken().SupportsAllDrives(true).Context(ctx).Do()
+		if err != nil {
+			return nil, driveError(err)
+		}
+		// the changes made before the start, as while restarting
+		versions = s.fileVersions(ctx, ids)
+		token = start.StartPageToken
+	default:
+		var err error
+		versions, token, err = s.changedVersions(ctx, ids, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageToken = token
+	keys := make([]string, 0, len(s.files))
+	for key := range s.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changed := []syncedFile{}
+	for _, key := range keys {
+		f := s.files[key]
+		if v, ok := versions[f.FileId]; ok && f.mark(v) {
+			if err := s.save(f); err != nil {
+				log.Printf("saving the synced file %s: %v", f.Req.FileUrl, err)
+			}
+		}
+		if f.Pending != nil {
+			changed = append(changed, *f)
+		}
+	}
+	return changed, nil
+}
+
+// changedVersions lists the last version of the files in every page of changes from the page token,
+// returning the page token of the next poll
+func (s *DriveSync) changedVersions(ctx context.Context, ids map[string]bool, pageToken string) (map[string]version, string, error) {
+	versions := make(map[string]version)
+	newToken := pageToken
+	for token := pageToken; token != ""; {
+		cl, err := s.drive.Changes.List(token).
+			Fields("nextPageToken, newStartPageToken, changes(fileId, removed, file(modifiedTime, md5Checksum, trashed))").
+			SupportsAllDrives(true).
+			IncludeItemsFromAllDrives(true).
+			Context(ctx).
+			Do()
+		if err != nil {
+			return nil, "", driveError(err)
+		}
+		for _, c := range cl.Changes {
+			if !ids[c.FileId] || c.Removed || c.File == nil || c.File.Trashed {
+				continue
+			}
+			versions[c.FileId] = version{c.File.ModifiedTime, c.File.Md5Checksum}
+		}
+		if cl.NewStartPageToken != "" {
+			newToken = cl.NewStartPageToken
+		}
+		token = cl.NextPageToken
+	}
+	return versions, newToken, nil
+}
+
+// fileVersions gets the version of every file, skipping the ones failing
+func (s *DriveSync) fileVersions(ctx context.Context, ids map[string]bool) map[string]version {
+	versions := make(map[string]version)
+	for id := range ids {
+		f, err := s.drive.Files.Get(id).SupportsAllDrives(true).Fields("modifiedTime, md5Checksum, trashed").Context(ctx).Do()
+		if err != nil {
+			log.Printf("getting the version of %s: %v", id, driveError(err))
+			continue
+		}
+		if f.Trashed {
+			continue
+		}
+		versions[id] = version{f.ModifiedTime, f.Md5Checksum}
+	}
+	return versions
+}
+
+func (s *DriveSync) fileIds() map[string]bool {
+	ids := make(map[string]bool)
+	for _, f := range s.files {
+		ids[f.FileId] = true
+	}
+	return ids
+}
+
+func (s *DriveSync) save(f *syncedFile) error {
+	if s.store == nil {
+		return nil
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(syncKey(f.Kind, f.FileId), b)
+}
+
+// syncKey identifies a file synced for a kind
+func syncKey(kind, fileId string) string {
+	return kind + "/" + fileId
+}
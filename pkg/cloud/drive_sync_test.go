@@ -0,0 +1,282 @@
+package cloud_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/cloud"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const otherId = "1OtherxxxxxxxxxxxxxxxxxxxxxxxxxxA"
+
+// changesDrive stubs the Drive files and Changes API, page "1" renames the synced file,
+// page "2" edits it twice and edits another file, page "2b" fails while failPage is set,
+// the listing waits for a value and another one on block when set
+type changesDrive struct {
+	modifiedTime string
+	failPage     bool
+	block        chan struct{}
+}
+
+func newChangesDrive(t *testing.T, cd *changesDrive) *drive.Service {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/"+docId, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"modifiedTime":%q}`, cd.modifiedTime)
+	})
+	mux.HandleFunc("/changes/startPageToken", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"startPageToken":"1"}`)
+	})
+	mux.HandleFunc("/changes", func(w http.ResponseWriter, r *http.Request) {
+		if cd.block != nil {
+			<-cd.block
+			<-cd.block
+		}
+		w.Header().Set("content-type", "application/json")
+		switch r.URL.Query().Get("pageToken") {
+		case "1":
+			fmt.Fprintf(w, `{"newStartPageToken":"2","changes":[{"fileId":%q,"file":{"modifiedTime":"2021-01-01T00:00:00Z"}}]}`, docId)
+		case "2":
+			fmt.Fprintf(w, `{"nextPageToken":"2b","changes":[{"fileId":%q,"file":{"modifiedTime":"2021-01-02T00:00:00Z"}}]}`, docId)
+		case "2b":
+			if cd.failPage {
+				cd.failPage = false
+				http.Error(w, `{"error":{"code":500,"message":"backend error"}}`, http.StatusInternalServerError)
+				return
+			}
+			fmt.Fprintf(w, `{"newStartPageToken":"3","changes":[{"fileId":%q,"file":{"modifiedTime":"2021-01-03T00:00:00Z"}},{"fileId":%q,"file":{"modifiedTime":"2021-01-03T00:00:00Z"}}]}`, docId, otherId)
+		default:
+			fmt.Fprint(w, `{"newStartPageToken":"3","changes":[]}`)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ds, err := drive.NewService(context.Background(), option.WithAPIKey("key"), option.WithEndpoint(srv.URL+"/"))
+	assert.Nil(t, err)
+	return ds
+}
+
+// stubSyncStore keeps the synced files in memory
+type stubSyncStore map[string][]byte
+
+func (ss stubSyncStore) Put(key string, value []byte) error {
+	ss[key] = value
+	return nil
+}
+
+func (ss stubSyncStore) Delete(key string) error {
+	delete(ss, key)
+	return nil
+}
+
+func (ss stubSyncStore) All() (map[string][]byte, error) {
+	return ss, nil
+}
+
+// recordImports records the kind and url of each import, failing while fail is set
+func recordImports(sync *cloud.DriveSync, imported *[]string, fail *bool) {
+	sync.OnChange(func(ctx context.Context, kind string, req sending.SendReq) error {
+		if fail != nil && *fail {
+			return errors.New("import failed")
+		}
+		*imported = append(*imported, kind+" "+req.FileUrl)
+		return nil
+	})
+}
+
+func TestDriveSync_Poll(t *testing.T) {
+	ctx := context.Background()
+	fileUrl := "gdrive://" + docId
+	sync, err := cloud.NewDriveSync(newChangesDrive(t, &changesDrive{modifiedTime: "2021-01-01T00:00:00Z"}), 0)
+	assert.Nil(t, err)
+	var imported []string
+	recordImports(sync, &imported, nil)
+	assert.Nil(t, sync.Register(ctx, "devotionals", sending.SendReq{FileUrl: fileUrl}))
+	assert.Nil(t, sync.Register(ctx, "topics", sending.SendReq{FileUrl: fileUrl}))
+
+	t.Run("it starts tracking the changes", func(t *testing.T) {
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("it skips the changes without new content", func(t *testing.T) {
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("it imports the changed files once for each kind", func(t *testing.T) {
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{fileUrl, fileUrl}, urls)
+		assert.Equal(t, []string{"devotionals " + fileUrl, "topics " + fileUrl}, imported)
+	})
+
+	t.Run("it stops importing the unregistered files", func(t *testing.T) {
+		assert.Nil(t, sync.Unregister("devotionals", fileUrl))
+		assert.Nil(t, sync.Unregister("topics", fileUrl))
+
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("it only syncs Google Drive files", func(t *testing.T) {
+		err := sync.Register(ctx, "devotionals", sending.SendReq{FileUrl: "https://example.com/" + docId})
+
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+	})
+}
+
+func TestDriveSync_Retry(t *testing.T) {
+	ctx := context.Background()
+	fileUrl := "gdrive://" + docId
+	cd := &changesDrive{modifiedTime: "2021-01-01T00:00:00Z", failPage: true}
+	sync, err := cloud.NewDriveSync(newChangesDrive(t, cd), 0)
+	assert.Nil(t, err)
+	var imported []string
+	fail := true
+	recordImports(sync, &imported, &fail)
+	assert.Nil(t, sync.Register(ctx, "devotionals", sending.SendReq{FileUrl: fileUrl}))
+	for i := 0; i < 2; i++ {
+		_, err := sync.Poll(ctx)
+		assert.Nil(t, err)
+	}
+
+	t.Run("it lists the changes again when a page fails", func(t *testing.T) {
+		_, err := sync.Poll(ctx)
+		assert.NotNil(t, err)
+
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("it imports again the files whose import failed", func(t *testing.T) {
+		fail = false
+
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{fileUrl}, urls)
+		assert.Equal(t, []string{"devotionals " + fileUrl}, imported)
+	})
+
+	t.Run("it records the version once imported", func(t *testing.T) {
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+}
+
+func TestDriveSync_FilePolling(t *testing.T) {
+	ctx := context.Background()
+	fileUrl := "gdrive://" + docId
+	cd := &changesDrive{modifiedTime: "2021-01-01T00:00:00Z"}
+	sync, err := cloud.NewDriveSync(newChangesDrive(t, cd), 0, cloud.WithFilePolling())
+	assert.Nil(t, err)
+	var imported []string
+	recordImports(sync, &imported, nil)
+	assert.Nil(t, sync.Register(ctx, "devotionals", sending.SendReq{FileUrl: fileUrl}))
+
+	t.Run("it skips the files without new content", func(t *testing.T) {
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("it imports the files whose version changed", func(t *testing.T) {
+		cd.modifiedTime = "2021-01-02T00:00:00Z"
+
+		urls, err := sync.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{fileUrl}, urls)
+		urls, err = sync.Poll(ctx)
+		assert.Nil(t, err)
+		assert.Empty(t, urls)
+	})
+}
+
+func TestDriveSync_Store(t *testing.T) {
+	ctx := context.Background()
+	fileUrl := "gdrive://" + docId
+	cd := &changesDrive{modifiedTime: "2021-01-01T00:00:00Z"}
+	ds := newChangesDrive(t, cd)
+	store := stubSyncStore{}
+	req := sending.SendReq{PlanId: "plan", FileUrl: fileUrl, Upsert: true}
+
+	sync, err := cloud.NewDriveSync(ds, 0, cloud.WithSyncStore(store))
+	assert.Nil(t, err)
+	assert.Nil(t, sync.Register(ctx, "devotionals", req))
+
+	t.Run("it imports the stored files changed while stopped", func(t *testing.T) {
+		cd.modifiedTime = "2021-01-02T00:00:00Z"
+		restarted, err := cloud.NewDriveSync(ds, 0, cloud.WithSyncStore(store))
+		assert.Nil(t, err)
+		var reqs []sending.SendReq
+		restarted.OnChange(func(ctx context.Context, kind string, req sending.SendReq) error {
+			reqs = append(reqs, req)
+			return nil
+		})
+
+		urls, err := restarted.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{fileUrl}, urls)
+		assert.Equal(t, []sending.SendReq{req}, reqs)
+	})
+
+	t.Run("it removes the unregistered files", func(t *testing.T) {
+		assert.Nil(t, sync.Unregister("devotionals", fileUrl))
+
+		assert.Empty(t, store)
+	})
+}
+
+func TestDriveSync_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	fileUrl := "gdrive://" + docId
+	cd := &changesDrive{modifiedTime: "2021-01-01T00:00:00Z"}
+	sync, err := cloud.NewDriveSync(newChangesDrive(t, cd), 0)
+	assert.Nil(t, err)
+	assert.Nil(t, sync.Register(ctx, "devotionals", sending.SendReq{FileUrl: fileUrl}))
+	_, err = sync.Poll(ctx)
+	assert.Nil(t, err)
+
+	t.Run("it registers the files while listing the changes", func(t *testing.T) {
+		cd.block = make(chan struct{})
+		polled := make(chan error)
+		go func() {
+			_, err := sync.Poll(ctx)
+			polled <- err
+		}()
+		cd.block <- struct{}{}
+
+		err := sync.Register(ctx, "topics", sending.SendReq{FileUrl: fileUrl})
+
+		cd.block <- struct{}{}
+		assert.Nil(t, err)
+		assert.Nil(t, <-polled)
+	})
+}
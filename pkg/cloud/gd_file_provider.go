@@ -1,38 +1,71 @@
 package cloud
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"regexp"
+	"strings"
 
 	feed "github.com/amelendres/go-feeder/pkg"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
-var ErrNotFoundGoogleDriveFileId = func(url string) error {
-	return fmt.Errorf("Url <%s> does not have the file id", url)
+// defaultMaxSize limits the downloaded files, read whole in memory
+const defaultMaxSize = 20 << 20
+
+var (
+	ErrNotFoundGoogleDriveFileId = func(url string) error {
+		return feed.NewError(feed.ErrCodeInvalidFileUrl, fmt.Errorf("Url <%s> does not have the file id", url))
+	}
+	ErrFileTooLarge = func(maxSize int64) error {
+		return feed.NewError(feed.ErrCodeFileTooLarge, fmt.Errorf("file exceeds %d bytes", maxSize))
+	}
+)
+
+type Option func(*GDFileProvider)
+
+// WithMaxSize limits the size of the downloaded and exported files
+func WithMaxSize(bytes int64) Option {
+	return func(fp *GDFileProvider) {
+		fp.maxSize = bytes
+	}
 }
 
 type GDFileProvider struct {
-	drive *drive.Service
-	file  *os.File
+	drive   *drive.Service
+	file    *os.File
+	maxSize int64
+}
+
+func NewGDFileProvider(ds *drive.Service, opts ...Option) feed.FileProvider {
+	fp := &GDFileProvider{drive: ds, maxSize: defaultMaxSize}
+	for _, opt := range opts {
+		opt(fp)
+	}
+	return fp
 }
 
-func NewGDFileProvider(ds *drive.Service) feed.FileProvider {
+// NewGDFolder lists the documents of Google Drive folders, including the shared drives ones
+func NewGDFolder(ds *drive.Service) feed.Folder {
 
-	return &GDFileProvider{ds, nil}
+	return &GDFileProvider{drive: ds, maxSize: defaultMaxSize}
 }
 
-func (fp *GDFileProvider) File(url string) (io.Reader, error) {
+func (fp *GDFileProvider) File(ctx context.Context, url string) (io.Reader, error) {
 
 	fileId, err := fp.fileId(url)
 	if err != nil {
 		return nil, err
 	}
 
-	file, err := fp.download(fileId)
+	file, err := fp.download(ctx, fileId)
 	if err != nil {
 		return nil, err
 	}
@@ -40,17 +73,102 @@ func (fp *GDFileProvider) File(url string) (io.Reader, error) {
 	return file, nil
 }
 
-func (fp *GDFileProvider) download(fileId string) (io.Reader, error) {
+// exportMimeTypes converts native Google Docs and Sheets to the formats the parsers read
+var exportMimeTypes = map[string]string{
+	"application/vnd.google-apps.document":    "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.google-apps.spreadsheet": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+const (
+	googleAppsMimeType = "application/vnd.google-apps."
+	folderMimeType     = "application/vnd.google-apps.folder"
+)
+
+var ErrUnsupportedGoogleFile = func(fileId, mimeType string) error {
+	return feed.NewError(feed.ErrCodeUnsupportedFile, fmt.Errorf("Google Drive file <%s> of type <%s> can not be exported", fileId, mimeType))
+}
+
+func (fp *GDFileProvider) download(ctx context.Context, fileId string) (io.Reader, error) {
+
+	f, err := fp.drive.Files.Get(fileId).SupportsAllDrives(true).Fields("mimeType").Context(ctx).Do()
+	if err != nil {
+		return nil, driveError(err)
+	}
+
+	var resp *http.Response
+	if exportType, ok := exportMimeTypes[f.MimeType]; ok {
+		resp, err = fp.drive.Files.Export(fileId, exportType).Context(ctx).Download()
+	} else if strings.HasPrefix(f.MimeType, googleAppsMimeType) {
+		return nil, ErrUnsupportedGoogleFile(fileId, f.MimeType)
+	} else {
+		resp, err = fp.drive.Files.Get(fileId).SupportsAllDrives(true).Context(ctx).Download()
+	}
+	if err != nil {
+		return nil, driveError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength > fp.maxSize {
+		return nil, ErrFileTooLarge(fp.maxSize)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, fp.maxSize+1))
+	if err != nil {
+		return nil, driveError(err)
+	}
+	if int64(len(b)) > fp.maxSize {
+		return nil, ErrFileTooLarge(fp.maxSize)
+	}
+	return bytes.NewReader(b), nil
+}
+
+// Files lists the documents of the folder, skipping its subfolders
+func (fp *GDFileProvider) Files(ctx context.Context, url string) ([]feed.FolderFile, error) {
 
-	//drive
-	fgc := fp.drive.Files.Get(fileId)
-	resp, err := fgc.Download()
+	folderId, err := fp.fileId(url)
 	if err != nil {
 		return nil, err
 	}
-	// defer resp.Body.Close()
 
-	return resp.Body, nil
+	files := []feed.FolderFile{}
+	err = fp.drive.Files.List().
+		Q(fmt.Sprintf("'%s' in parents and trashed = false", folderId)).
+		Fields("nextPageToken, files(id, name, mimeType)").
+		OrderBy("name").
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Pages(ctx, func(fl *drive.FileList) error {
+			for _, f := range fl.Files {
+				if f.MimeType == folderMimeType {
+					continue
+				}
+				files = append(files, feed.FolderFile{Name: f.Name, Url: "gdrive://" + f.Id})
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, driveError(err)
+	}
+	return files, nil
+}
+
+// driveError classifies a Drive failure, a file without access is not found as Drive answers 403 for it,
+// unless Drive is limiting the rate of the requests
+func driveError(err error) error {
+	e, ok := err.(*googleapi.Error)
+	if ok && (e.Code == http.StatusNotFound || e.Code == http.StatusForbidden && !rateLimited(e)) {
+		return feed.NewError(feed.ErrCodeFileNotFound, err)
+	}
+	return feed.NewError(feed.ErrCodeUnavailable, err)
+}
+
+func rateLimited(e *googleapi.Error) bool {
+	for _, item := range e.Errors {
+		switch item.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "dailyLimitExceeded", "sharingRateLimitExceeded":
+			return true
+		}
+	}
+	return false
 }
 
 func (fp *GDFileProvider) Name() string {
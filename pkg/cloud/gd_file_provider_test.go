@@ -0,0 +1,158 @@
+package cloud_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const (
+	docId    = "1DocxxxxxxxxxxxxxxxxxxxxxxxxxxxxA"
+	sheetId  = "1SheetxxxxxxxxxxxxxxxxxxxxxxxxxxA"
+	slidesId = "1SlidesxxxxxxxxxxxxxxxxxxxxxxxxxA"
+	folderId = "1FolderxxxxxxxxxxxxxxxxxxxxxxxxxA"
+	// bigId is a 32 bytes docx, privateId is not shared and limitedId is rate limited
+	bigId     = "1BigxxxxxxxxxxxxxxxxxxxxxxxxxxxxA"
+	privateId = "1PrivatexxxxxxxxxxxxxxxxxxxxxxxxA"
+	limitedId = "1LimitedxxxxxxxxxxxxxxxxxxxxxxxxA"
+)
+
+// newNativeDrive stubs the Drive metadata and export endpoints of native Google files
+func newNativeDrive(t *testing.T) *drive.Service {
+	t.Helper()
+	mimeTypes := map[string]string{
+		docId:    "application/vnd.google-apps.document",
+		sheetId:  "application/vnd.google-apps.spreadsheet",
+		slidesId: "application/vnd.google-apps.presentation",
+	}
+	mux := http.NewServeMux()
+	for id, mimeType := range mimeTypes {
+		id, mimeType := id, mimeType
+		mux.HandleFunc("/files/"+id, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("alt") == "media" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("content-type", "application/json")
+			fmt.Fprintf(w, `{"mimeType":%q}`, mimeType)
+		})
+		mux.HandleFunc("/files/"+id+"/export", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s exported as %s", id, r.URL.Query().Get("mimeType"))
+		})
+	}
+	mux.HandleFunc("/files/"+bigId, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("alt") == "media" {
+			fmt.Fprint(w, strings.Repeat("x", 32))
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprint(w, `{"mimeType":"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}`)
+	})
+	mux.HandleFunc("/files/"+privateId, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"code":403,"message":"forbidden","errors":[{"reason":"forbidden"}]}}`)
+	})
+	mux.HandleFunc("/files/"+limitedId, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":{"code":403,"message":"rate limit","errors":[{"reason":"userRateLimitExceeded"}]}}`)
+	})
+	mux.HandleFunc("/files", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		if r.URL.Query().Get("q") != "'"+folderId+"' in parents and trashed = false" {
+			fmt.Fprint(w, `{"files":[]}`)
+			return
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			fmt.Fprintf(w, `{"nextPageToken":"next","files":[{"id":%q,"name":"2021.docx","mimeType":"application/vnd.google-apps.document"}]}`, docId)
+			return
+		}
+		fmt.Fprintf(w, `{"files":[{"id":"sub","name":"drafts","mimeType":"application/vnd.google-apps.folder"},{"id":%q,"name":"topics.xlsx","mimeType":"application/vnd.google-apps.spreadsheet"}]}`, sheetId)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ds, err := drive.NewService(context.Background(), option.WithAPIKey("key"), option.WithEndpoint(srv.URL+"/"))
+	assert.Nil(t, err)
+	return ds
+}
+
+func TestGDFileProvider_File(t *testing.T) {
+	fp := cloud.NewGDFileProvider(newNativeDrive(t))
+
+	t.Run("it exports Google Docs to docx", func(t *testing.T) {
+		r, err := fp.File(context.Background(), "gdrive://"+docId)
+
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, docId+" exported as application/vnd.openxmlformats-officedocument.wordprocessingml.document", string(b))
+	})
+
+	t.Run("it exports Google Sheets to xlsx", func(t *testing.T) {
+		r, err := fp.File(context.Background(), "https://docs.google.com/spreadsheets/d/"+sheetId+"/edit")
+
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, sheetId+" exported as application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", string(b))
+	})
+
+	t.Run("it rejects other Google files", func(t *testing.T) {
+		_, err := fp.File(context.Background(), "gdrive://"+slidesId)
+
+		assert.Equal(t, feed.ErrCodeUnsupportedFile, feed.Code(err))
+	})
+
+	t.Run("it limits the size of the files", func(t *testing.T) {
+		r, err := fp.File(context.Background(), "gdrive://"+bigId)
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Len(t, b, 32)
+
+		_, err = cloud.NewGDFileProvider(newNativeDrive(t), cloud.WithMaxSize(31)).File(context.Background(), "gdrive://"+bigId)
+
+		assert.Equal(t, feed.ErrCodeFileTooLarge, feed.Code(err))
+	})
+
+	t.Run("it does not find the files without access", func(t *testing.T) {
+		_, err := fp.File(context.Background(), "gdrive://"+privateId)
+
+		assert.Equal(t, feed.ErrCodeFileNotFound, feed.Code(err))
+	})
+
+	t.Run("it is unavailable while rate limited", func(t *testing.T) {
+		_, err := fp.File(context.Background(), "gdrive://"+limitedId)
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+	})
+}
+
+func TestGDFolder_Files(t *testing.T) {
+	folder := cloud.NewGDFolder(newNativeDrive(t))
+
+	t.Run("it lists every page of the folder documents", func(t *testing.T) {
+		files, err := folder.Files(context.Background(), "https://drive.google.com/drive/folders/"+folderId)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []feed.FolderFile{
+			{Name: "2021.docx", Url: "gdrive://" + docId},
+			{Name: "topics.xlsx", Url: "gdrive://" + sheetId},
+		}, files)
+	})
+
+	t.Run("it fails without the folder id", func(t *testing.T) {
+		_, err := folder.Files(context.Background(), "https://drive.google.com/drive/my-drive")
+
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+	})
+}
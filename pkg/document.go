@@ -0,0 +1,46 @@
+package feed
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// DocumentType is the kind of file of a document, told by its first bytes
+type DocumentType string
+
+const (
+	// DocumentZip is a docx, xlsx or odt, told apart by their entries
+	DocumentZip DocumentType = "zip"
+	// DocumentOLE is a Word 97 doc
+	DocumentOLE  DocumentType = "ole"
+	DocumentPDF  DocumentType = "pdf"
+	DocumentRTF  DocumentType = "rtf"
+	DocumentHTML DocumentType = "html"
+)
+
+var documentMagics = []struct {
+	magic []byte
+	typ   DocumentType
+}{
+	{[]byte("PK\x03\x04"), DocumentZip},
+	{[]byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), DocumentOLE},
+	{[]byte("%PDF-"), DocumentPDF},
+	{[]byte(`{\rtf`), DocumentRTF},
+}
+
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// DetectDocument tells the type of a document by its magic bytes, the html pages and fragments
+// are sniffed from their first 512 bytes
+func DetectDocument(head []byte) (DocumentType, bool) {
+	for _, m := range documentMagics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.typ, true
+		}
+	}
+	if strings.HasPrefix(http.DetectContentType(bytes.TrimPrefix(head, utf8BOM)), "text/html") {
+		return DocumentHTML, true
+	}
+	return "", false
+}
@@ -0,0 +1,32 @@
+package feed_test
+
+import (
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectDocument(t *testing.T) {
+	for head, want := range map[string]feed.DocumentType{
+		"PK\x03\x04word":                       feed.DocumentZip,
+		"\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1 doc": feed.DocumentOLE,
+		"%PDF-1.4":                             feed.DocumentPDF,
+		`{\rtf1\ansi`:                          feed.DocumentRTF,
+		"<!DOCTYPE html><html></html>":         feed.DocumentHTML,
+		"\xEF\xBB\xBF<html><body></body>":      feed.DocumentHTML,
+		"\n<p>001</p><p>EL CAMINO</p>":         feed.DocumentHTML,
+		"<div>001</div>":                       feed.DocumentHTML,
+	} {
+		typ, ok := feed.DetectDocument([]byte(head))
+
+		assert.True(t, ok, head)
+		assert.Equal(t, want, typ, head)
+	}
+
+	for _, head := range []string{"plain text is not a document", "<xml/>", ""} {
+		_, ok := feed.DetectDocument([]byte(head))
+
+		assert.False(t, ok, head)
+	}
+}
@@ -0,0 +1,57 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ErrorCode string
+
+const (
+	ErrCodeInvalidRequest  ErrorCode = "invalid_request"
+	ErrCodeInvalidFileUrl  ErrorCode = "invalid_file_url"
+	ErrCodeNotFound        ErrorCode = "not_found"
+	ErrCodeFileNotFound    ErrorCode = "file_not_found"
+	ErrCodeUnreadableFile  ErrorCode = "unreadable_file"
+	ErrCodeFileTooLarge    ErrorCode = "file_too_large"
+	ErrCodeUnsupportedFile ErrorCode = "unsupported_file"
+	ErrCodeUnknownFeed     ErrorCode = "unknown_feed"
+	ErrCodeRejected        ErrorCode = "rejected"
+	ErrCodeUnavailable     ErrorCode = "unavailable"
+	ErrCodeInternal        ErrorCode = "internal"
+)
+
+// ErrUnknownKind is a kind of feeds without parser and sender
+var ErrUnknownKind = NewError(ErrCodeNotFound, errors.New("unknown kind of feeds"))
+
+// ErrPanic reports an unexpected failure recovered from a panic
+var ErrPanic = func(v interface{}) error {
+	return NewError(ErrCodeInternal, fmt.Errorf("unexpected failure: %v", v))
+}
+
+// Error classifies an error with a stable code
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func NewError(code ErrorCode, err error) error {
+	return &Error{code, err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the code of the first classified error in the chain
+func Code(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ErrCodeInternal
+}
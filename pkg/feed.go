@@ -10,4 +10,6 @@ type UnknownItem struct {
 type ParsedItems struct {
 	UnknownItems []UnknownItem
 	Items        []Item
+	// Checksum is the sha256 of the parsed file
+	Checksum string
 }
@@ -1,19 +1,41 @@
 package feed
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"regexp"
+	"io"
+	"net/url"
+	"strings"
 )
 
 const (
-	fs_provider = "fs"
-	gd_provider = "gd"
+	fs_provider   = "fs"
+	gd_provider   = "gd"
+	http_provider = "http"
 )
 
+// schemeProviders names the provider of each file url scheme,
+// any other scheme is provided by the provider with the same name
+var schemeProviders = map[string]string{
+	"":       fs_provider,
+	"file":   fs_provider,
+	"gdrive": gd_provider,
+	"http":   http_provider,
+	"https":  http_provider,
+}
+
+// googleHosts are the Google Drive share link hosts
+var googleHosts = map[string]bool{
+	"docs.google.com":  true,
+	"drive.google.com": true,
+}
+
 var ErrUnknownFeed = errors.New("unknown feed")
 
 type Feeder interface {
-	Feeds(path string) (*ParsedItems, error)
+	Feeds(ctx context.Context, path string) (*ParsedItems, error)
 	Destination(d *Destination)
 }
 
@@ -21,6 +43,7 @@ type feeder struct {
 	fileProviders map[string]FileProvider
 	parser        Parser
 	feeds         []Item
+	format        Format
 }
 
 func NewFeeder(p Parser, providers []FileProvider) Feeder {
@@ -38,27 +61,70 @@ func NewFeeder(p Parser, providers []FileProvider) Feeder {
 }
 
 func (s *feeder) Destination(d *Destination) {
+	s.format = d.Format
 	s.parser.Destination(d)
 }
 
-func (s *feeder) Feeds(path string) (*ParsedItems, error) {
-	prov := fs_provider
-	if isGoogleDrive(path) {
-		prov = gd_provider
+func (s *feeder) Feeds(ctx context.Context, path string) (*ParsedItems, error) {
+	if err := s.format.Validate(); err != nil {
+		return nil, err
 	}
-	f, err := s.fileProviders[prov].File(path)
+	if err := s.writes(s.format); err != nil {
+		return nil, err
+	}
+	fp, ok := s.fileProviders[ProviderName(path)]
+	if !ok {
+		return nil, NewError(ErrCodeInvalidFileUrl, ErrUnknownFile)
+	}
+	f, err := fp.File(ctx, path)
 
 	if err != nil {
 		return nil, err
 	}
-	return s.parser.Parse(f)
+	if c, ok := f.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	h := sha256.New()
+	feeds, err := s.parser.Parse(ctx, io.TeeReader(f, h))
+	if err != nil {
+		return nil, err
+	}
+	//hash what the parser did not read
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, NewError(ErrCodeUnreadableFile, err)
+	}
+	feeds.Checksum = hex.EncodeToString(h.Sum(nil))
+	return feeds, nil
+}
+
+// writes fails with a rich text format the parser does not write, rather than ignoring it
+func (s *feeder) writes(f Format) error {
+	if f == FormatText {
+		return nil
+	}
+	if rp, ok := s.parser.(RichTextParser); ok && rp.Writes(f) {
+		return nil
+	}
+	return ErrUnsupportedFormat(f)
 }
 
 func (s *feeder) AddProvider(p FileProvider) {
 	s.fileProviders[p.Name()] = p
 }
 
-func isGoogleDrive(path string) bool {
-	re := regexp.MustCompile("docs.google.com")
-	return len(re.FindAllString(path, 1)) == 1
+// ProviderName resolves the provider of the file url by its scheme, plain paths are local files
+func ProviderName(path string) string {
+	u, err := url.Parse(path)
+	if err != nil || len(u.Scheme) < 2 {
+		return fs_provider
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme == "http" || scheme == "https") && googleHosts[strings.ToLower(u.Hostname())] {
+		return gd_provider
+	}
+	if name, ok := schemeProviders[scheme]; ok {
+		return name
+	}
+	return scheme
 }
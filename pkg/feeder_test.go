@@ -0,0 +1,152 @@
+package feed_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubProvider answers its own name as the file
+type stubProvider struct {
+	name string
+}
+
+func (sp stubProvider) File(ctx context.Context, path string) (io.Reader, error) {
+	return strings.NewReader(sp.name), nil
+}
+
+func (sp stubProvider) Name() string {
+	return sp.name
+}
+
+// closingProvider answers a file recording whether it was closed
+type closingProvider struct {
+	file *closingFile
+}
+
+type closingFile struct {
+	io.Reader
+	closed bool
+}
+
+func (cf *closingFile) Close() error {
+	cf.closed = true
+	return nil
+}
+
+func (cp closingProvider) File(ctx context.Context, path string) (io.Reader, error) {
+	cp.file.Reader = strings.NewReader(path)
+	cp.file.closed = false
+	return cp.file, nil
+}
+
+func (cp closingProvider) Name() string {
+	return "fs"
+}
+
+// stubParser parses the file as an item titled with its content
+type stubParser struct{}
+
+func (stubParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	b := new(strings.Builder)
+	io.Copy(b, r)
+	return &feed.ParsedItems{Items: []feed.Item{{"title": b.String()}}}, nil
+}
+
+func (stubParser) Destination(d *feed.Destination) {}
+
+// htmlParser is a stubParser writing html
+type htmlParser struct {
+	stubParser
+}
+
+func (htmlParser) Writes(f feed.Format) bool {
+	return f == feed.FormatHTML
+}
+
+// failingParser fails parsing every file
+type failingParser struct {
+	stubParser
+}
+
+func (failingParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	return nil, errors.New("parse failed")
+}
+
+func TestFeeder_Feeds(t *testing.T) {
+	feeder := feed.NewFeeder(stubParser{}, []feed.FileProvider{
+		stubProvider{"fs"}, stubProvider{"gd"}, stubProvider{"http"}, stubProvider{"upload"},
+	})
+
+	t.Run("it resolves the provider by the url scheme", func(t *testing.T) {
+		for path, provider := range map[string]string{
+			"../docs/2021.docx":                                    "fs",
+			"/docs/2021.docx":                                      "fs",
+			"file:///docs/2021.docx":                               "fs",
+			"gdrive://1XI0cxe6T1VSipeeCmEbk14VDkZM5PS_c":           "gd",
+			"https://docs.google.com/document/d/1XI0cxe6T1VSipee/": "gd",
+			"https://cms.example.org/2021.docx":                    "http",
+			"http://cloud.example.org/s/2021/download":             "http",
+			"upload://7c1a":                                        "upload",
+		} {
+			feeds, err := feeder.Feeds(context.Background(), path)
+
+			assert.Nil(t, err)
+			assert.Equal(t, provider, feeds.Items[0]["title"], path)
+			assert.NotEmpty(t, feeds.Checksum)
+		}
+	})
+
+	t.Run("it fails without the scheme provider", func(t *testing.T) {
+		_, err := feeder.Feeds(context.Background(), "ftp://example.org/2021.docx")
+
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+	})
+	t.Run("it fails with an unknown format", func(t *testing.T) {
+		d := feed.NewDestination("plan", "publisher", "author")
+		d.Format = "pdf"
+		feeder.Destination(d)
+		defer feeder.Destination(&feed.Destination{})
+
+		_, err := feeder.Feeds(context.Background(), "2021.docx")
+
+		assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+	})
+	t.Run("it fails with a format the parser does not write", func(t *testing.T) {
+		d := feed.NewDestination("plan", "publisher", "author")
+		d.Format = feed.FormatMarkdown
+		for _, p := range []feed.Parser{stubParser{}, htmlParser{}} {
+			feeder := feed.NewFeeder(p, []feed.FileProvider{stubProvider{"fs"}})
+			feeder.Destination(d)
+
+			_, err := feeder.Feeds(context.Background(), "2021.docx")
+
+			assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+		}
+	})
+	t.Run("it writes the formats of the parser", func(t *testing.T) {
+		d := feed.NewDestination("plan", "publisher", "author")
+		d.Format = feed.FormatHTML
+		feeder := feed.NewFeeder(htmlParser{}, []feed.FileProvider{stubProvider{"fs"}})
+		feeder.Destination(d)
+
+		_, err := feeder.Feeds(context.Background(), "2021.docx")
+
+		assert.Nil(t, err)
+	})
+	t.Run("it closes the file", func(t *testing.T) {
+		for _, p := range []feed.Parser{stubParser{}, failingParser{}} {
+			cp := closingProvider{&closingFile{}}
+			feeder := feed.NewFeeder(p, []feed.FileProvider{cp})
+
+			feeder.Feeds(context.Background(), "2021.docx")
+
+			assert.True(t, cp.file.closed)
+		}
+	})
+}
@@ -1,15 +1,28 @@
 package feeding
 
 import (
+	"context"
+
 	feed "github.com/amelendres/go-feeder/pkg"
 )
 
 type FeedReq struct {
 	PlanId, AuthorId, PublisherId, FileUrl string
+	Upsert                                 bool
+	Format                                 feed.Format
+	FieldMap                               string
 }
 
 type Service interface {
-	Feeds(req FeedReq) (*feed.ParsedItems, error)
+	Feeds(ctx context.Context, req FeedReq) (*feed.ParsedItems, error)
+}
+
+// Factory builds a service for each request, as the parsers keep its destination
+type Factory func() Service
+
+// Shared reuses the service in every request, only for the requests that never run concurrently
+func Shared(s Service) Factory {
+	return func() Service { return s }
 }
 
 type service struct {
@@ -20,7 +33,11 @@ func NewService(f feed.Feeder) Service {
 	return &service{feeder: f}
 }
 
-func (s *service) Feeds(req FeedReq) (*feed.ParsedItems, error) {
-	s.feeder.Destination(feed.NewDestination(req.PlanId, req.PublisherId, req.AuthorId))
-	return s.feeder.Feeds(req.FileUrl)
+func (s *service) Feeds(ctx context.Context, req FeedReq) (*feed.ParsedItems, error) {
+	d := feed.NewDestination(req.PlanId, req.PublisherId, req.AuthorId)
+	d.Upsert = req.Upsert
+	d.Format = req.Format
+	d.FieldMap = req.FieldMap
+	s.feeder.Destination(d)
+	return s.feeder.Feeds(ctx, req.FileUrl)
 }
@@ -1,6 +1,7 @@
 package feed
 
 import (
+	"context"
 	"errors"
 	"io"
 )
@@ -8,6 +9,6 @@ import (
 var ErrUnknownFile = errors.New("unknown file")
 
 type FileProvider interface {
-	File(path string) (io.Reader, error)
+	File(ctx context.Context, path string) (io.Reader, error)
 	Name() string
 }
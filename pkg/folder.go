@@ -0,0 +1,16 @@
+package feed
+
+import "context"
+
+// FolderFile is a document of a folder, read by the folder provider with its Url
+type FolderFile struct {
+	Name string
+	Url  string
+}
+
+// Folder provides the documents of the folders it lists
+type Folder interface {
+	FileProvider
+	// Files lists the documents of the folder url, sorted by name
+	Files(ctx context.Context, url string) ([]FolderFile, error)
+}
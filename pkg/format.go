@@ -0,0 +1,29 @@
+package feed
+
+import "fmt"
+
+// Format of the rich text of the items, as their content
+type Format string
+
+const (
+	FormatText     Format = ""
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+var ErrUnknownFormat = func(f Format) error {
+	return NewError(ErrCodeInvalidRequest, fmt.Errorf("unknown format <%s>, want markdown or html", f))
+}
+
+var ErrUnsupportedFormat = func(f Format) error {
+	return NewError(ErrCodeInvalidRequest, fmt.Errorf("format <%s> is not supported by this kind of feed", f))
+}
+
+// Validate fails with a format the parsers do not write
+func (f Format) Validate() error {
+	switch f {
+	case FormatText, FormatMarkdown, FormatHTML:
+		return nil
+	}
+	return ErrUnknownFormat(f)
+}
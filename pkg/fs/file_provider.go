@@ -1,25 +1,63 @@
 package fs
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	feed "github.com/amelendres/go-feeder/pkg"
 )
 
+// defaultMaxSize limits the zip entries, read whole in memory
+const defaultMaxSize = 20 << 20
+
+var ErrFileTooLarge = func(maxSize int64) error {
+	return feed.NewError(feed.ErrCodeFileTooLarge, fmt.Errorf("file exceeds %d bytes", maxSize))
+}
+
+type Option func(*FileProvider)
+
+// WithMaxSize limits the size of the zip entries
+func WithMaxSize(bytes int64) Option {
+	return func(fp *FileProvider) {
+		fp.maxSize = bytes
+	}
+}
+
 type FileProvider struct {
-	file *os.File
+	file    *os.File
+	maxSize int64
 }
 
-func NewFileProvider() feed.FileProvider {
-	return &FileProvider{}
+func NewFileProvider(opts ...Option) feed.FileProvider {
+	return newFileProvider(opts)
 }
 
-func (fp *FileProvider) File(path string) (io.Reader, error) {
+func newFileProvider(opts []Option) *FileProvider {
+	fp := &FileProvider{maxSize: defaultMaxSize}
+	for _, opt := range opts {
+		opt(fp)
+	}
+	return fp
+}
+
+func (fp *FileProvider) File(ctx context.Context, path string) (io.Reader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path = strings.TrimPrefix(path, "file://")
+	if archive, entry, ok := SplitZipEntry(path); ok {
+		return fp.zipFile(archive, entry)
+	}
 	file, err := os.Open(path)
 
+	if os.IsNotExist(err) {
+		return nil, feed.NewError(feed.ErrCodeFileNotFound, err)
+	}
 	if err != nil {
-		return nil, err
+		return nil, feed.NewError(feed.ErrCodeUnreadableFile, err)
 	}
 	return file, nil
 }
@@ -0,0 +1,242 @@
+package fs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+// zipEntry separates the archive from the entry in the file urls, as "2021.zip!/january.docx"
+const zipEntry = "!/"
+
+var ErrInvalidPattern = func(pattern string, err error) error {
+	return feed.NewError(feed.ErrCodeInvalidFileUrl, fmt.Errorf("invalid pattern <%s>: %w", pattern, err))
+}
+
+// FileFeeds are the parsed items of a document of a directory or zip archive, or its failure
+type FileFeeds struct {
+	File  string            `json:"file"`
+	Feeds *feed.ParsedItems `json:"feeds,omitempty"`
+	Error string            `json:"error,omitempty"`
+	Code  feed.ErrorCode    `json:"code,omitempty"`
+}
+
+// SplitZipEntry splits the url of a zip entry into the path of its archive and its name
+func SplitZipEntry(url string) (archive, entry string, ok bool) {
+	p := strings.TrimPrefix(url, "file://")
+	i := strings.Index(p, zipEntry)
+	if i < 0 {
+		return p, "", false
+	}
+	return p[:i], p[i+len(zipEntry):], true
+}
+
+// NewFolder lists the documents of local directories and zip archives
+func NewFolder(opts ...Option) feed.Folder {
+	return newFileProvider(opts)
+}
+
+// IsFolder tells whether the url is a local directory, glob pattern or zip archive
+func IsFolder(url string) bool {
+	if feed.ProviderName(url) != "fs" {
+		return false
+	}
+	p := strings.TrimPrefix(url, "file://")
+	if strings.Contains(p, zipEntry) || isZip(p) || isPattern(p) {
+		return true
+	}
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
+
+// Feeds parses every document of the directory or zip archive with the parser for the destination,
+// going on after a failing one unless the destination is invalid
+func Feeds(ctx context.Context, p feed.Parser, d *feed.Destination, url string) ([]FileFeeds, error) {
+	fp := newFileProvider(nil)
+	files, err := fp.Files(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	feeder := feed.NewFeeder(p, []feed.FileProvider{fp})
+	feeder.Destination(d)
+	feeds := make([]FileFeeds, 0, len(files))
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return feeds, err
+		}
+		ff := FileFeeds{File: f.Name}
+		ff.Feeds, err = feeder.Feeds(ctx, f.Url)
+		if err != nil && feed.Code(err) == feed.ErrCodeInvalidRequest {
+			return nil, err
+		}
+		if err != nil {
+			ff.Error = err.Error()
+			ff.Code = feed.Code(err)
+		}
+		feeds = append(feeds, ff)
+	}
+	return feeds, nil
+}
+
+// Files lists the documents of a directory, the ones matching a glob pattern as "2021/*.docx",
+// or the entries of a zip archive, filtered as "2021.zip!/*.docx"
+func (fp *FileProvider) Files(ctx context.Context, url string) ([]feed.FolderFile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p := strings.TrimPrefix(url, "file://")
+
+	if archive, pattern, _ := SplitZipEntry(p); isZip(archive) {
+		return zipFiles(archive, pattern)
+	}
+
+	matches, err := matches(p)
+	if err != nil {
+		return nil, err
+	}
+	files := []feed.FolderFile{}
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil || info.IsDir() || hidden(info.Name()) {
+			continue
+		}
+		files = append(files, feed.FolderFile{Name: filepath.Base(m), Url: m})
+	}
+	return files, nil
+}
+
+// matches lists the paths matching the glob pattern, or the ones of the directory
+func matches(p string) ([]string, error) {
+	if isPattern(p) {
+		m, err := filepath.Glob(p)
+		if err != nil {
+			return nil, ErrInvalidPattern(p, err)
+		}
+		return m, nil
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	if !info.IsDir() {
+		return []string{}, nil
+	}
+	entries, err := ioutil.ReadDir(p)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	m := make([]string, 0, len(entries))
+	for _, e := range entries {
+		m = append(m, filepath.Join(p, e.Name()))
+	}
+	return m, nil
+}
+
+func zipFiles(archive, pattern string) ([]feed.FolderFile, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	defer zr.Close()
+
+	// an entry named as the pattern is read as is
+	literal := false
+	for _, f := range zr.File {
+		literal = literal || f.Name == pattern
+	}
+
+	files := []feed.FolderFile{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || hidden(path.Base(f.Name)) || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		if literal && f.Name != pattern {
+			continue
+		}
+		if pattern != "" && !literal {
+			ok, err := path.Match(pattern, f.Name)
+			if err != nil {
+				return nil, ErrInvalidPattern(pattern, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		files = append(files, feed.FolderFile{Name: f.Name, Url: archive + zipEntry + f.Name})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// zipFile reads the whole entry up to the max size, the archive is closed once read
+func (fp *FileProvider) zipFile(archive, name string) (*bytes.Reader, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fileError(err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		if f.UncompressedSize64 > uint64(fp.maxSize) {
+			return nil, ErrFileTooLarge(fp.maxSize)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, feed.NewError(feed.ErrCodeUnreadableFile, err)
+		}
+		defer rc.Close()
+		// the declared size may lie
+		b, err := ioutil.ReadAll(io.LimitReader(rc, fp.maxSize+1))
+		if err != nil {
+			return nil, feed.NewError(feed.ErrCodeUnreadableFile, err)
+		}
+		if int64(len(b)) > fp.maxSize {
+			return nil, ErrFileTooLarge(fp.maxSize)
+		}
+		return bytes.NewReader(b), nil
+	}
+	return nil, feed.NewError(feed.ErrCodeFileNotFound, fmt.Errorf("%s has no entry <%s>", archive, name))
+}
+
+func fileError(err error) error {
+	if os.IsNotExist(err) {
+		return feed.NewError(feed.ErrCodeFileNotFound, err)
+	}
+	if errors.Is(err, zip.ErrFormat) {
+		return feed.NewError(feed.ErrCodeUnsupportedFile, err)
+	}
+	return feed.NewError(feed.ErrCodeUnreadableFile, err)
+}
+
+func isZip(p string) bool {
+	return strings.EqualFold(filepath.Ext(p), ".zip")
+}
+
+// isPattern tells a glob pattern from a path, the existing files named with its meta characters are paths
+func isPattern(p string) bool {
+	if !strings.ContainsAny(p, `*?[`) {
+		return false
+	}
+	_, err := os.Stat(p)
+	return os.IsNotExist(err)
+}
+
+// hidden files are the dot files and the Office lock files
+func hidden(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$")
+}
@@ -0,0 +1,185 @@
+package fs_test
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubParser parses the whole file as an item, unknown when empty
+type stubParser struct{}
+
+func (sp stubParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{{ItemError: "empty"}}}, nil
+	}
+	return &feed.ParsedItems{Items: []feed.Item{{"content": string(b)}}}, nil
+}
+
+func (sp stubParser) Destination(d *feed.Destination) {}
+
+func newDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"february.docx":  "february",
+		"january.docx":   "january",
+		"notes.txt":      "",
+		".DS_Store":      "",
+		"~$january.docx": "",
+	} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "drafts"), 0755))
+	return dir
+}
+
+func newZip(t *testing.T) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "2021.zip")
+	f, err := os.Create(archive)
+	assert.Nil(t, err)
+	zw := zip.NewWriter(f)
+	for _, entry := range [][2]string{
+		{"2021/march.docx", "march"},
+		{"2021/april.docx", "april"},
+		{"2021/readme.txt", ""},
+		{"__MACOSX/2021/._march.docx", ""},
+	} {
+		w, err := zw.Create(entry[0])
+		assert.Nil(t, err)
+		w.Write([]byte(entry[1]))
+	}
+	assert.Nil(t, zw.Close())
+	assert.Nil(t, f.Close())
+	return archive
+}
+
+func TestFolder_Files(t *testing.T) {
+	dir := newDir(t)
+	archive := newZip(t)
+	folder := fs.NewFolder()
+
+	t.Run("it lists the directory documents", func(t *testing.T) {
+		files, err := folder.Files(context.Background(), dir)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []feed.FolderFile{
+			{Name: "february.docx", Url: filepath.Join(dir, "february.docx")},
+			{Name: "january.docx", Url: filepath.Join(dir, "january.docx")},
+			{Name: "notes.txt", Url: filepath.Join(dir, "notes.txt")},
+		}, files)
+	})
+
+	t.Run("it filters the directory with a glob", func(t *testing.T) {
+		files, err := folder.Files(context.Background(), filepath.Join(dir, "*.docx"))
+
+		assert.Nil(t, err)
+		assert.Len(t, files, 2)
+	})
+
+	t.Run("it lists and reads the zip entries", func(t *testing.T) {
+		files, err := folder.Files(context.Background(), archive+"!/*/*.docx")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []feed.FolderFile{
+			{Name: "2021/april.docx", Url: archive + "!/2021/april.docx"},
+			{Name: "2021/march.docx", Url: archive + "!/2021/march.docx"},
+		}, files)
+
+		r, err := folder.File(context.Background(), files[1].Url)
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "march", string(b))
+	})
+
+	t.Run("it does not find a missing directory", func(t *testing.T) {
+		_, err := folder.Files(context.Background(), filepath.Join(dir, "missing"))
+
+		assert.Equal(t, feed.ErrCodeFileNotFound, feed.Code(err))
+	})
+
+	t.Run("it does not find a missing zip entry", func(t *testing.T) {
+		_, err := folder.File(context.Background(), archive+"!/2021/may.docx")
+
+		assert.Equal(t, feed.ErrCodeFileNotFound, feed.Code(err))
+	})
+
+	t.Run("it refuses a zip entry over the max size", func(t *testing.T) {
+		_, err := fs.NewFolder(fs.WithMaxSize(4)).File(context.Background(), archive+"!/2021/march.docx")
+
+		assert.Equal(t, feed.ErrCodeFileTooLarge, feed.Code(err))
+	})
+
+	t.Run("it lists a directory named with glob characters", func(t *testing.T) {
+		drafts := filepath.Join(t.TempDir(), "2021 [draft]")
+		assert.Nil(t, os.Mkdir(drafts, 0755))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(drafts, "may?.docx"), []byte("may"), 0644))
+
+		files, err := folder.Files(context.Background(), drafts)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []feed.FolderFile{{Name: "may?.docx", Url: filepath.Join(drafts, "may?.docx")}}, files)
+		assert.True(t, fs.IsFolder(drafts))
+		assert.False(t, fs.IsFolder(filepath.Join(drafts, "may?.docx")))
+	})
+}
+
+func TestFeeds(t *testing.T) {
+	t.Run("it parses every zip entry", func(t *testing.T) {
+		feeds, err := fs.Feeds(context.Background(), stubParser{}, &feed.Destination{}, newZip(t))
+
+		assert.Nil(t, err)
+		assert.Len(t, feeds, 3)
+		assert.Equal(t, "2021/april.docx", feeds[0].File)
+		assert.Equal(t, []feed.Item{{"content": "april"}}, feeds[0].Feeds.Items)
+		assert.NotEmpty(t, feeds[0].Feeds.Checksum)
+		assert.Len(t, feeds[2].Feeds.UnknownItems, 1)
+	})
+
+	t.Run("it goes on after a failing file", func(t *testing.T) {
+		dir := newDir(t)
+		assert.Nil(t, os.Chmod(filepath.Join(dir, "january.docx"), 0))
+		if _, err := os.Open(filepath.Join(dir, "january.docx")); err == nil {
+			t.Skip("the files are readable without permissions")
+		}
+
+		feeds, err := fs.Feeds(context.Background(), stubParser{}, &feed.Destination{}, filepath.Join(dir, "*.docx"))
+
+		assert.Nil(t, err)
+		assert.Len(t, feeds, 2)
+		assert.Empty(t, feeds[0].Error)
+		assert.Equal(t, feed.ErrCodeUnreadableFile, feeds[1].Code)
+	})
+
+	t.Run("it fails with a format the parser does not write", func(t *testing.T) {
+		d := &feed.Destination{Format: feed.FormatHTML}
+
+		_, err := fs.Feeds(context.Background(), stubParser{}, d, newZip(t))
+
+		assert.Equal(t, feed.ErrCodeInvalidRequest, feed.Code(err))
+	})
+}
+
+func TestIsFolder(t *testing.T) {
+	dir := newDir(t)
+
+	assert.True(t, fs.IsFolder(dir))
+	assert.True(t, fs.IsFolder("file://"+dir))
+	assert.True(t, fs.IsFolder("2021/*.docx"))
+	assert.True(t, fs.IsFolder("2021.zip"))
+	assert.False(t, fs.IsFolder(filepath.Join(dir, "january.docx")))
+	assert.False(t, fs.IsFolder("https://example.com/2021.zip"))
+}
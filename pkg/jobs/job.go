@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/sending"
+)
+
+type State string
+
+const (
+	Queued  State = "queued"
+	Parsing State = "parsing"
+	Sending State = "sending"
+	Done    State = "done"
+	Failed  State = "failed"
+)
+
+var ErrJobNotFound = feed.NewError(feed.ErrCodeNotFound, errors.New("job not found"))
+
+type Job struct {
+	Id           string             `json:"id"`
+	State        State              `json:"state"`
+	Sent         int                `json:"sent"`
+	Failed       int                `json:"failed"`
+	Errors       []string           `json:"errors"`
+	Code         feed.ErrorCode     `json:"code,omitempty"`
+	UnknownItems []feed.UnknownItem `json:"unknownItems,omitempty"`
+	Report       *feed.Report       `json:"report,omitempty"`
+	// Folder is the report of a folder job, whose Sent and Failed count its documents
+	Folder    *batch.Report   `json:"folder,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	Req       sending.SendReq `json:"-"`
+	FolderReq *batch.Req      `json:"-"`
+}
+
+// Store keeps the import jobs, implementations must be safe for concurrent use
+type Store interface {
+	Save(job Job) error
+	Job(id string) (*Job, error)
+}
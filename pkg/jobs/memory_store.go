@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"sync"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]Job
+}
+
+func NewMemoryStore() Store {
+	return &MemoryStore{jobs: make(map[string]Job)}
+}
+
+func (ms *MemoryStore) Save(job Job) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	job.Errors = append([]string(nil), job.Errors...)
+	job.UnknownItems = append([]feed.UnknownItem(nil), job.UnknownItems...)
+	if job.Report != nil {
+		report := *job.Report
+		report.Items = append([]feed.ItemReport(nil), report.Items...)
+		job.Report = &report
+	}
+	ms.jobs[job.Id] = job
+	return nil
+}
+
+func (ms *MemoryStore) Job(id string) (*Job, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	job, ok := ms.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	job.Errors = append([]string(nil), job.Errors...)
+	return &job, nil
+}
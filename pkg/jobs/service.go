@@ -0,0 +1,225 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/google/uuid"
+)
+
+const (
+	queueSize    = 64
+	waitInterval = 100 * time.Millisecond
+)
+
+var ErrQueueFull = feed.NewError(feed.ErrCodeUnavailable, errors.New("import queue is full"))
+
+type Service interface {
+	Enqueue(req sending.SendReq) (*Job, error)
+	// EnqueueFolder imports every document of the folder in a single job
+	EnqueueFolder(req batch.Req) (*Job, error)
+	Job(id string) (*Job, error)
+}
+
+type service struct {
+	ctx       context.Context
+	store     Store
+	newFeeder feeding.Factory
+	newSender sending.Factory
+	folders   batch.Service
+	queue     chan Job
+}
+
+type Option func(*service)
+
+// WithFolders imports the folder jobs with the batch service
+func WithFolders(bs batch.Service) Option {
+	return func(s *service) { s.folders = bs }
+}
+
+// NewService starts a pool of workers importing the enqueued jobs until ctx is done,
+// cancelling the running ones.
+// Each job parses and sends with its own services, as they keep the destination of the job.
+func NewService(ctx context.Context, store Store, fs feeding.Factory, ss sending.Factory, workers int, opts ...Option) Service {
+	s := &service{
+		ctx:       ctx,
+		store:     store,
+		newFeeder: fs,
+		newSender: ss,
+		queue:     make(chan Job, queueSize),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+
+	return s
+}
+
+// Wait polls the job until it is done or failed, or ctx is done
+func Wait(ctx context.Context, s Service, id string) (*Job, error) {
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+	for {
+		job, err := s.Job(id)
+		if err != nil {
+			return nil, err
+		}
+		if job.State == Done || job.State == Failed {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) Enqueue(req sending.SendReq) (*Job, error) {
+	return s.enqueue(newJob(req, nil))
+}
+
+func (s *service) EnqueueFolder(req batch.Req) (*Job, error) {
+	if s.folders == nil {
+		return nil, batch.ErrUnknownFolder
+	}
+	return s.enqueue(newJob(sending.SendReq{}, &req))
+}
+
+func newJob(req sending.SendReq, folderReq *batch.Req) Job {
+	now := time.Now()
+	return Job{
+		Id:        uuid.New().String(),
+		State:     Queued,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Req:       req,
+		FolderReq: folderReq,
+	}
+}
+
+func (s *service) enqueue(job Job) (*Job, error) {
+	if err := s.store.Save(job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- job:
+	default:
+		// the saved job would stay queued forever
+		s.fail(&job, ErrQueueFull)
+		return nil, ErrQueueFull
+	}
+
+	return &job, nil
+}
+
+func (s *service) Job(id string) (*Job, error) {
+	return s.store.Job(id)
+}
+
+func (s *service) work() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case job := <-s.queue:
+			s.run(job)
+		}
+	}
+}
+
+func (s *service) run(job Job) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("job <%s> panics: %v\n%s", job.Id, v, debug.Stack())
+			s.fail(&job, feed.ErrPanic(v))
+		}
+	}()
+
+	if job.FolderReq != nil {
+		s.runFolder(job)
+		return
+	}
+
+	s.update(&job, Parsing)
+	feeds, err := s.newFeeder().Feeds(s.ctx, feeding.FeedReq(job.Req))
+	if err != nil {
+		s.fail(&job, err)
+		return
+	}
+
+	if len(feeds.UnknownItems) > 0 {
+		job.Failed = len(feeds.UnknownItems)
+		s.fail(&job, &sending.UnknownFeedError{UnknownItems: feeds.UnknownItems})
+		return
+	}
+
+	s.update(&job, Sending)
+	report, err := s.newSender().SendItems(s.ctx, job.Req, feeds)
+	job.Report = report
+	if report != nil {
+		job.Failed = report.Count(feed.OutcomeFailed)
+		job.Sent = len(report.Items) - job.Failed
+	}
+	if err != nil {
+		if report == nil {
+			job.Failed = len(feeds.Items)
+		}
+		s.fail(&job, err)
+		return
+	}
+
+	s.update(&job, Done)
+}
+
+// runFolder imports the documents of the folder, the job is done when the folder is read,
+// even if some documents fail as told by its report
+func (s *service) runFolder(job Job) {
+	s.update(&job, Sending)
+	report, err := s.folders.Send(s.ctx, *job.FolderReq)
+	job.Folder = report
+	if report != nil {
+		job.Failed = report.Failed
+		job.Sent = len(report.Documents) - report.Failed
+	}
+	if err != nil {
+		s.fail(&job, err)
+		return
+	}
+
+	s.update(&job, Done)
+}
+
+func (s *service) fail(job *Job, err error) {
+	job.Errors = append(job.Errors, err.Error())
+	job.Code = feed.Code(err)
+
+	var unknown *sending.UnknownFeedError
+	if errors.As(err, &unknown) {
+		job.UnknownItems = unknown.UnknownItems
+	}
+	s.update(job, Failed)
+}
+
+func (s *service) update(job *Job, state State) {
+	job.State = state
+	job.UpdatedAt = time.Now()
+	if err := s.store.Save(*job); err != nil {
+		log.Printf("fails saving job <%s>: %s\n", job.Id, err)
+	}
+}
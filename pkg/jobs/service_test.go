@@ -0,0 +1,255 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubFeeder struct {
+	feeds *feed.ParsedItems
+	err   error
+}
+
+func (sf *stubFeeder) Feeds(ctx context.Context, req feeding.FeedReq) (*feed.ParsedItems, error) {
+	return sf.feeds, sf.err
+}
+
+type stubSender struct {
+	err error
+}
+
+func (ss *stubSender) Send(ctx context.Context, req sending.SendReq) (*feed.Report, error) {
+	return nil, ss.err
+}
+
+func (ss *stubSender) SendItems(ctx context.Context, req sending.SendReq, feeds *feed.ParsedItems) (*feed.Report, error) {
+	if ss.err != nil {
+		return nil, ss.err
+	}
+	report := feed.NewReport()
+	for _, item := range feeds.Items {
+		report.Add(item, feed.OutcomeCreated, nil, nil)
+	}
+	return report, nil
+}
+
+func (ss *stubSender) Plan(ctx context.Context, req sending.SendReq) (*feed.Report, error) {
+	return nil, ss.err
+}
+
+// blockingFeeder parses nothing until it is released
+type blockingFeeder struct {
+	release chan struct{}
+}
+
+func (bf *blockingFeeder) Feeds(ctx context.Context, req feeding.FeedReq) (*feed.ParsedItems, error) {
+	<-bf.release
+	return &feed.ParsedItems{}, nil
+}
+
+// recordingStore remembers the failed jobs
+type recordingStore struct {
+	jobs.Store
+	mu     sync.Mutex
+	failed []string
+}
+
+func (rs *recordingStore) Save(job jobs.Job) error {
+	if job.State == jobs.Failed {
+		rs.mu.Lock()
+		rs.failed = append(rs.failed, job.Id)
+		rs.mu.Unlock()
+	}
+	return rs.Store.Save(job)
+}
+
+func (rs *recordingStore) Failed() []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]string(nil), rs.failed...)
+}
+
+func TestService_Import(t *testing.T) {
+	items := []feed.Item{{"title": "one"}, {"title": "two"}}
+
+	t.Run("it sends parsed items", func(t *testing.T) {
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(&stubFeeder{feeds: &feed.ParsedItems{Items: items}}), sending.Shared(&stubSender{}), 1)
+
+		job, err := js.Enqueue(sending.SendReq{FileUrl: "file.docx"})
+		assert.Nil(t, err)
+		assert.Equal(t, jobs.Queued, job.State)
+
+		job = waitJob(t, js, job.Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Equal(t, 2, job.Sent)
+		assert.Equal(t, 2, job.Report.Count(feed.OutcomeCreated))
+		assert.Empty(t, job.Errors)
+	})
+
+	t.Run("it fails with unknown items", func(t *testing.T) {
+		feeds := &feed.ParsedItems{
+			Items:        items,
+			UnknownItems: []feed.UnknownItem{{Item: []string{"3"}, ItemError: "Invalid day"}},
+		}
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(&stubFeeder{feeds: feeds}), sending.Shared(&stubSender{}), 1)
+
+		job, _ := js.Enqueue(sending.SendReq{FileUrl: "file.docx"})
+
+		job = waitJob(t, js, job.Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.Equal(t, 1, job.Failed)
+		assert.Equal(t, []string{sending.ErrUnknownFeed.Error()}, job.Errors)
+		assert.Equal(t, feed.ErrCodeUnknownFeed, job.Code)
+		assert.Equal(t, feeds.UnknownItems, job.UnknownItems)
+	})
+
+	t.Run("it fails sending items", func(t *testing.T) {
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(&stubFeeder{feeds: &feed.ParsedItems{Items: items}}), sending.Shared(&stubSender{errors.New("boom")}), 1)
+
+		job, _ := js.Enqueue(sending.SendReq{FileUrl: "file.docx"})
+
+		job = waitJob(t, js, job.Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.Equal(t, 0, job.Sent)
+		assert.Equal(t, []string{"boom"}, job.Errors)
+		assert.Equal(t, feed.ErrCodeInternal, job.Code)
+	})
+
+	t.Run("it fails the jobs it cannot queue", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		store := &recordingStore{Store: jobs.NewMemoryStore()}
+		js := jobs.NewService(context.Background(), store, feeding.Shared(&blockingFeeder{release}), sending.Shared(&stubSender{}), 1)
+
+		var err error
+		for i := 0; err == nil; i++ {
+			_, err = js.Enqueue(sending.SendReq{FileUrl: "file.docx"})
+			assert.True(t, i < 100, "the queue never fills")
+		}
+
+		assert.Equal(t, jobs.ErrQueueFull, err)
+		failed := store.Failed()
+		assert.Equal(t, 1, len(failed))
+		job, _ := store.Job(failed[0])
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.Equal(t, feed.ErrCodeUnavailable, job.Code)
+	})
+
+	t.Run("it does not find a job", func(t *testing.T) {
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(&stubFeeder{}), sending.Shared(&stubSender{}), 1)
+
+		job, err := js.Job("does-not-exist")
+		assert.Nil(t, job)
+		assert.Equal(t, feed.ErrCodeNotFound, feed.Code(err))
+	})
+}
+
+// stubFolders imports the folders with a fixed report
+type stubFolders struct {
+	report *batch.Report
+	err    error
+}
+
+func (sf *stubFolders) Send(ctx context.Context, req batch.Req) (*batch.Report, error) {
+	return sf.report, sf.err
+}
+
+func (sf *stubFolders) Plan(ctx context.Context, req batch.Req) (*batch.Report, error) {
+	return sf.report, sf.err
+}
+
+func TestService_ImportFolder(t *testing.T) {
+	report := &batch.Report{Documents: []batch.DocumentReport{{File: "2021.docx"}, {File: "2020.docx", Error: "boom"}}, Failed: 1}
+
+	t.Run("it imports the folder documents as a job", func(t *testing.T) {
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), nil, nil, 1, jobs.WithFolders(&stubFolders{report: report}))
+
+		job, err := js.EnqueueFolder(batch.Req{FolderUrl: "stub://folder"})
+		assert.Nil(t, err)
+
+		job = waitJob(t, js, job.Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Equal(t, 1, job.Sent)
+		assert.Equal(t, 1, job.Failed)
+		assert.Equal(t, report, job.Folder)
+	})
+
+	t.Run("it fails the job of an unreadable folder", func(t *testing.T) {
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), nil, nil, 1, jobs.WithFolders(&stubFolders{err: batch.ErrUnknownFolder}))
+
+		job, _ := js.EnqueueFolder(batch.Req{FolderUrl: "stub://folder"})
+
+		job = waitJob(t, js, job.Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, job.Code)
+	})
+
+	t.Run("it rejects folders without the folder service", func(t *testing.T) {
+		js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), nil, nil, 1)
+
+		_, err := js.EnqueueFolder(batch.Req{FolderUrl: "stub://folder"})
+
+		assert.Equal(t, batch.ErrUnknownFolder, err)
+	})
+}
+
+func TestWait(t *testing.T) {
+	bf := &blockingFeeder{release: make(chan struct{})}
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(bf), sending.Shared(&stubSender{}), 1)
+	job, err := js.Enqueue(sending.SendReq{FileUrl: "file.docx"})
+	assert.Nil(t, err)
+
+	t.Run("it stops waiting when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := jobs.Wait(ctx, js, job.Id)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("it waits for the job to finish", func(t *testing.T) {
+		close(bf.release)
+
+		job, err := jobs.Wait(context.Background(), js, job.Id)
+
+		assert.Nil(t, err)
+		assert.Equal(t, jobs.Done, job.State)
+	})
+
+	t.Run("it fails with an unknown job", func(t *testing.T) {
+		_, err := jobs.Wait(context.Background(), js, "unknown")
+
+		assert.Equal(t, jobs.ErrJobNotFound, err)
+	})
+}
+
+func waitJob(t *testing.T, js jobs.Service, id string) *jobs.Job {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		job, err := js.Job(id)
+		if err != nil {
+			t.Fatalf("Unable to get job <%s>, '%v'", id, err)
+		}
+		if job.State == jobs.Done || job.State == jobs.Failed {
+			return job
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("Job <%s> not finished, state %s", id, job.State)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -1,8 +1,18 @@
 package feed
 
-import "io"
+import (
+	"context"
+	"io"
+)
 
 type Parser interface {
-	Parse(r io.Reader) (*ParsedItems, error)
+	Parse(ctx context.Context, r io.Reader) (*ParsedItems, error)
 	Destination(d *Destination)
 }
+
+// RichTextParser is a Parser writing the rich text of the items in formats other than the plain text
+type RichTextParser interface {
+	Parser
+	// Writes tells whether the parser writes the items in the format
+	Writes(f Format) bool
+}
@@ -0,0 +1,51 @@
+package feed
+
+type Outcome string
+
+const (
+	OutcomeCreated   Outcome = "created"
+	OutcomeAttached  Outcome = "attached"
+	OutcomeSkipped   Outcome = "skipped-existing"
+	OutcomeUpdated   Outcome = "updated"
+	OutcomeUnchanged Outcome = "unchanged"
+	OutcomeFailed    Outcome = "failed"
+)
+
+// ItemReport is the outcome of sending an Item, with the changes done and the failure
+type ItemReport struct {
+	Item    Item     `json:"item"`
+	Outcome Outcome  `json:"outcome"`
+	Error   string   `json:"error,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+type Report struct {
+	Items []ItemReport `json:"items"`
+}
+
+func NewReport() *Report {
+	return &Report{Items: []ItemReport{}}
+}
+
+// Add reports an item, it fails whenever err is not nil
+func (r *Report) Add(item Item, outcome Outcome, changes []Change, err error) {
+	ir := ItemReport{Item: item, Outcome: outcome, Changes: changes}
+	if ir.Changes == nil {
+		ir.Changes = []Change{}
+	}
+	if err != nil {
+		ir.Outcome = OutcomeFailed
+		ir.Error = err.Error()
+	}
+	r.Items = append(r.Items, ir)
+}
+
+func (r *Report) Count(outcome Outcome) int {
+	count := 0
+	for _, item := range r.Items {
+		if item.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,175 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+const (
+	defaultEndpoint = "https://s3.amazonaws.com"
+	defaultRegion   = "us-east-1"
+	defaultTimeout  = 30 * time.Second
+	defaultMaxSize  = 20 << 20
+)
+
+var (
+	ErrInvalidObjectUrl = func(path string) error {
+		return feed.NewError(feed.ErrCodeInvalidFileUrl, fmt.Errorf("Url <%s> is not a s3://bucket/key object", path))
+	}
+	ErrFileTooLarge = func(maxSize int64) error {
+		return feed.NewError(feed.ErrCodeFileTooLarge, fmt.Errorf("object exceeds %d bytes", maxSize))
+	}
+	ErrGettingObject = func(path string, status int) error {
+		err := fmt.Errorf("fails getting <%s>, unexpected response status %d", path, status)
+		switch {
+		case status == http.StatusNotFound:
+			return feed.NewError(feed.ErrCodeFileNotFound, err)
+		case status >= 500 || status == http.StatusTooManyRequests:
+			return feed.NewError(feed.ErrCodeUnavailable, err)
+		}
+		return feed.NewError(feed.ErrCodeUnreadableFile, err)
+	}
+	ErrRequestingObject = func(err error) error {
+		return feed.NewError(feed.ErrCodeUnavailable, err)
+	}
+)
+
+type Option func(*FileProvider)
+
+// WithEndpoint addresses the buckets by path on the endpoint, as http://localhost:9000 for MinIO
+func WithEndpoint(endpoint string) Option {
+	return func(fp *FileProvider) {
+		fp.endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+func WithRegion(region string) Option {
+	return func(fp *FileProvider) {
+		fp.region = region
+	}
+}
+
+// WithCredentials signs the requests, the session token is only needed by temporary credentials
+func WithCredentials(accessKey, secretKey, sessionToken string) Option {
+	return func(fp *FileProvider) {
+		fp.accessKey = accessKey
+		fp.secretKey = secretKey
+		fp.sessionToken = sessionToken
+	}
+}
+
+// WithHTTPClient gets the objects with the client, its timeout is replaced
+func WithHTTPClient(c *http.Client) Option {
+	return func(fp *FileProvider) {
+		fp.client = c
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(fp *FileProvider) {
+		fp.timeout = d
+	}
+}
+
+func WithMaxSize(bytes int64) Option {
+	return func(fp *FileProvider) {
+		fp.maxSize = bytes
+	}
+}
+
+// FileProvider gets the objects of s3://bucket/key urls from a S3-compatible storage
+type FileProvider struct {
+	client                             *http.Client
+	timeout                            time.Duration
+	endpoint, region                   string
+	accessKey, secretKey, sessionToken string
+	maxSize                            int64
+	now                                func() time.Time
+}
+
+func NewFileProvider(opts ...Option) feed.FileProvider {
+	fp := &FileProvider{
+		client:   &http.Client{},
+		timeout:  defaultTimeout,
+		endpoint: defaultEndpoint,
+		region:   defaultRegion,
+		maxSize:  defaultMaxSize,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(fp)
+	}
+
+	client := *fp.client
+	client.Timeout = fp.timeout
+	fp.client = &client
+	return fp
+}
+
+func (fp *FileProvider) File(ctx context.Context, path string) (io.Reader, error) {
+	bucket, key, err := object(path)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fp.endpoint, nil)
+	if err != nil {
+		return nil, feed.NewError(feed.ErrCodeInvalidFileUrl, err)
+	}
+	req.URL.Path = "/" + bucket + "/" + key
+	req.URL.RawPath = "/" + uriEncode(bucket, true) + "/" + uriEncode(key, false)
+	if fp.accessKey != "" {
+		if fp.sessionToken != "" {
+			req.Header.Set("x-amz-security-token", fp.sessionToken)
+		}
+		sign(req, fp.accessKey, fp.secretKey, fp.region, fp.now())
+	}
+
+	resp, err := fp.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, ErrRequestingObject(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrGettingObject(path, resp.StatusCode)
+	}
+	if resp.ContentLength > fp.maxSize {
+		return nil, ErrFileTooLarge(fp.maxSize)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, fp.maxSize+1))
+	if err != nil {
+		return nil, ErrRequestingObject(err)
+	}
+	if int64(len(b)) > fp.maxSize {
+		return nil, ErrFileTooLarge(fp.maxSize)
+	}
+	return bytes.NewReader(b), nil
+}
+
+func (fp *FileProvider) Name() string {
+	return "s3"
+}
+
+// object splits a s3://bucket/key url, the key is taken as is, as it may hold any of # ? %
+func object(path string) (string, string, error) {
+	if !strings.HasPrefix(path, "s3://") {
+		return "", "", ErrInvalidObjectUrl(path)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(path, "s3://"), "/", 2)
+	if len(parts) < 2 || parts[0] == "" || strings.Trim(parts[1], "/") == "" {
+		return "", "", ErrInvalidObjectUrl(path)
+	}
+	return parts[0], parts[1], nil
+}
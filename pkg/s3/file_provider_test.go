@@ -0,0 +1,84 @@
+package s3_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+const credential = "Credential=minio/"
+
+// objects of the stub storage by their escaped path
+var objects = map[string]string{
+	"/manuscripts/2021/devocionales%20enero.docx":          "document",
+	"/manuscripts/2021/%231%20%C2%BFamor%3F%20100%25.docx": "special document",
+}
+
+// newStorage stubs a S3-compatible storage holding the objects
+func newStorage(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), credential) || r.Header.Get("x-amz-date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		object, ok := objects[r.URL.EscapedPath()]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, object)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFileProvider_File(t *testing.T) {
+	srv := newStorage(t)
+	fp := s3.NewFileProvider(s3.WithEndpoint(srv.URL), s3.WithCredentials("minio", "minio123", ""))
+
+	t.Run("it gets the object by path", func(t *testing.T) {
+		r, err := fp.File(context.Background(), "s3://manuscripts/2021/devocionales enero.docx")
+
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "document", string(b))
+	})
+
+	t.Run("it gets the object by a key with url reserved characters", func(t *testing.T) {
+		r, err := fp.File(context.Background(), "s3://manuscripts/2021/#1 ¿amor? 100%.docx")
+
+		assert.Nil(t, err)
+		b, _ := ioutil.ReadAll(r)
+		assert.Equal(t, "special document", string(b))
+	})
+
+	t.Run("it classifies the failures", func(t *testing.T) {
+		anonymous := s3.NewFileProvider(s3.WithEndpoint(srv.URL))
+
+		_, err := fp.File(context.Background(), "s3://manuscripts/2022.docx")
+		assert.Equal(t, feed.ErrCodeFileNotFound, feed.Code(err))
+		_, err = anonymous.File(context.Background(), "s3://manuscripts/2021/devocionales enero.docx")
+		assert.Equal(t, feed.ErrCodeUnreadableFile, feed.Code(err))
+		_, err = fp.File(context.Background(), "s3://manuscripts")
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+		_, err = fp.File(context.Background(), "s3:///2021.docx")
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+	})
+
+	t.Run("it limits the object size", func(t *testing.T) {
+		small := s3.NewFileProvider(s3.WithEndpoint(srv.URL), s3.WithCredentials("minio", "minio123", ""), s3.WithMaxSize(4))
+
+		_, err := small.File(context.Background(), "s3://manuscripts/2021/devocionales enero.docx")
+
+		assert.Equal(t, feed.ErrCodeFileTooLarge, feed.Code(err))
+	})
+}
@@ -1,16 +1,27 @@
 package feed
 
+import "context"
+
 type Destination struct {
 	PlanId      string
 	PublisherId string
 	AuthorId    string
+	// Upsert updates the already existing items instead of skipping them
+	Upsert bool
+	// Format writes the rich text of the items, plain text when empty
+	Format Format
+	// FieldMap names the column or field of each item key, as "day=Dia,title=Titulo", for the parsers reading them by name
+	FieldMap string
 }
 
 func NewDestination(planId, publisherId, authorId string) *Destination {
-	return &Destination{planId, publisherId, authorId}
+	return &Destination{PlanId: planId, PublisherId: publisherId, AuthorId: authorId}
 }
 
 type Sender interface {
-	Send(items []Item) error
+	// Send reports the outcome of each item, it fails when any item fails
+	Send(ctx context.Context, items []Item) (*Report, error)
+	// Plan reports what Send would do without modifying the destination
+	Plan(ctx context.Context, items []Item) (*Report, error)
 	Destination(d *Destination)
 }
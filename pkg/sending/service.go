@@ -1,40 +1,163 @@
 package sending
 
 import (
+	"context"
+	"errors"
+
 	feed "github.com/amelendres/go-feeder/pkg"
-	"github.com/pkg/errors"
 )
 
-var ErrUnknownFeed = errors.New("Unknown feeds")
+var ErrUnknownFeed = feed.NewError(feed.ErrCodeUnknownFeed, errors.New("Unknown feeds"))
+
+// UnknownFeedError holds the items the parser does not recognize
+type UnknownFeedError struct {
+	UnknownItems []feed.UnknownItem
+}
+
+func (e *UnknownFeedError) Error() string {
+	return ErrUnknownFeed.Error()
+}
+
+func (e *UnknownFeedError) Unwrap() error {
+	return ErrUnknownFeed
+}
 
 type Service interface {
-	Send(req SendReq) error
+	Send(ctx context.Context, req SendReq) (*feed.Report, error)
+	SendItems(ctx context.Context, req SendReq, feeds *feed.ParsedItems) (*feed.Report, error)
+	Plan(ctx context.Context, req SendReq) (*feed.Report, error)
+}
+
+// Factory builds a service for each import, as the parsers and senders keep its destination
+type Factory func() Service
+
+// Shared reuses the service in every import, only for the imports that never run concurrently
+func Shared(s Service) Factory {
+	return func() Service { return s }
 }
 
 type SendReq struct {
 	PlanId, AuthorId, PublisherId, FileUrl string
+	Upsert                                 bool
+	Format                                 feed.Format
+	FieldMap                               string
 }
 type service struct {
-	sender feed.Sender
-	feeder feed.Feeder
+	sender      feed.Sender
+	feeder      feed.Feeder
+	checkpoints feed.Checkpoints
 }
 
 func NewService(s feed.Sender, f feed.Feeder) Service {
 	return &service{sender: s, feeder: f}
 }
 
-func (ps *service) Send(req SendReq) error {
-	dest := feed.NewDestination(req.PlanId, req.PublisherId, req.AuthorId)
-	ps.feeder.Destination(dest)
-	feeds, err := ps.feeder.Feeds(req.FileUrl)
+// NewResumableService skips the items a previous import of the same file to the same destination already sent
+func NewResumableService(s feed.Sender, f feed.Feeder, cp feed.Checkpoints) Service {
+	return &service{sender: s, feeder: f, checkpoints: cp}
+}
+
+func (ps *service) Send(ctx context.Context, req SendReq) (*feed.Report, error) {
+	feeds, err := ps.feeds(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ps.SendItems(ctx, req, feeds)
+}
+
+// SendItems sends already parsed items to the request destination
+func (ps *service) SendItems(ctx context.Context, req SendReq, feeds *feed.ParsedItems) (*feed.Report, error) {
+	d := req.destination()
+	ps.sender.Destination(d)
+	if ps.checkpoints == nil || feeds.Checksum == "" {
+		return ps.sender.Send(ctx, feeds.Items)
+	}
+
+	importKey := feed.ImportKey(feeds.Checksum, d)
+	resumed, pending, err := ps.resume(importKey, feeds.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := ps.sender.Send(ctx, pending)
+	if report == nil {
+		return nil, err
+	}
+	var sent []string
+	for _, item := range report.Items {
+		if item.Outcome != feed.OutcomeFailed {
+			sent = append(sent, feed.ItemKey(item.Item))
+		}
+	}
+	if cpErr := ps.checkpoints.Save(importKey, sent...); cpErr != nil && err == nil {
+		err = cpErr
+	}
+
+	resumed.Items = append(resumed.Items, report.Items...)
+	return resumed, err
+}
+
+// Plan reports what Send would do, as a dry-run
+func (ps *service) Plan(ctx context.Context, req SendReq) (*feed.Report, error) {
+	feeds, err := ps.feeds(ctx, req)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	d := req.destination()
+	ps.sender.Destination(d)
+	if ps.checkpoints == nil || feeds.Checksum == "" {
+		return ps.sender.Plan(ctx, feeds.Items)
+	}
+
+	resumed, pending, err := ps.resume(feed.ImportKey(feeds.Checksum, d), feeds.Items)
+	if err != nil {
+		return nil, err
+	}
+	report, err := ps.sender.Plan(ctx, pending)
+	if report == nil {
+		return nil, err
+	}
+	resumed.Items = append(resumed.Items, report.Items...)
+	return resumed, err
+}
+
+// resume reports the items already sent by the import and returns the pending ones
+func (ps *service) resume(importKey string, items []feed.Item) (*feed.Report, []feed.Item, error) {
+	resumed := feed.NewReport()
+	var pending []feed.Item
+	for _, item := range items {
+		sent, err := ps.checkpoints.Sent(importKey, feed.ItemKey(item))
+		if err != nil {
+			return nil, nil, err
+		}
+		if sent {
+			resumed.Add(item, feed.OutcomeSkipped, nil, nil)
+			continue
+		}
+		pending = append(pending, item)
+	}
+	return resumed, pending, nil
+}
+
+func (ps *service) feeds(ctx context.Context, req SendReq) (*feed.ParsedItems, error) {
+	ps.feeder.Destination(req.destination())
+	feeds, err := ps.feeder.Feeds(ctx, req.FileUrl)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(feeds.UnknownItems) > 0 {
-		return ErrUnknownFeed
+		return nil, &UnknownFeedError{feeds.UnknownItems}
 	}
+	return feeds, nil
+}
 
-	ps.sender.Destination(dest)
-	return ps.sender.Send(feeds.Items)
+func (req SendReq) destination() *feed.Destination {
+	d := feed.NewDestination(req.PlanId, req.PublisherId, req.AuthorId)
+	d.Upsert = req.Upsert
+	d.Format = req.Format
+	d.FieldMap = req.FieldMap
+	return d
 }
@@ -0,0 +1,88 @@
+package sending_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/checkpoint"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/stretchr/testify/assert"
+)
+
+var errUnavailable = feed.NewError(feed.ErrCodeUnavailable, errors.New("devom is down"))
+
+// stubSender fails the items titled as failing
+type stubSender struct {
+	failing string
+	sent    []feed.Item
+}
+
+func (ss *stubSender) Send(ctx context.Context, items []feed.Item) (*feed.Report, error) {
+	report := feed.NewReport()
+	for _, item := range items {
+		if item["title"] == ss.failing {
+			report.Add(item, feed.OutcomeFailed, nil, errUnavailable)
+			continue
+		}
+		ss.sent = append(ss.sent, item)
+		report.Add(item, feed.OutcomeCreated, nil, nil)
+	}
+	if report.Count(feed.OutcomeFailed) > 0 {
+		return report, errUnavailable
+	}
+	return report, nil
+}
+
+func (ss *stubSender) Plan(ctx context.Context, items []feed.Item) (*feed.Report, error) {
+	return feed.NewReport(), nil
+}
+
+func (ss *stubSender) Destination(d *feed.Destination) {}
+
+func TestService_SendItems(t *testing.T) {
+	store, err := checkpoint.NewBoltStore(filepath.Join(t.TempDir(), "checkpoints.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	feeds := &feed.ParsedItems{
+		Items:    []feed.Item{{"title": "one"}, {"title": "two"}, {"title": "three"}},
+		Checksum: "checksum",
+	}
+	req := sending.SendReq{PlanId: "plan"}
+
+	t.Run("it keeps the sent items of a failing import", func(t *testing.T) {
+		sender := &stubSender{failing: "two"}
+		ss := sending.NewResumableService(sender, nil, store)
+
+		report, err := ss.SendItems(context.Background(), req, feeds)
+
+		assert.Equal(t, errUnavailable, err)
+		assert.Equal(t, 2, report.Count(feed.OutcomeCreated))
+		assert.Equal(t, 1, report.Count(feed.OutcomeFailed))
+	})
+
+	t.Run("it resumes from the failed items", func(t *testing.T) {
+		sender := &stubSender{}
+		ss := sending.NewResumableService(sender, nil, store)
+
+		report, err := ss.SendItems(context.Background(), req, feeds)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []feed.Item{{"title": "two"}}, sender.sent)
+		assert.Equal(t, 2, report.Count(feed.OutcomeSkipped))
+		assert.Equal(t, 1, report.Count(feed.OutcomeCreated))
+	})
+
+	t.Run("it sends again to another destination", func(t *testing.T) {
+		sender := &stubSender{}
+		ss := sending.NewResumableService(sender, nil, store)
+
+		_, err := ss.SendItems(context.Background(), sending.SendReq{PlanId: "another-plan"}, feeds)
+
+		assert.Nil(t, err)
+		assert.Equal(t, feeds.Items, sender.sent)
+	})
+}
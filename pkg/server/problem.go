@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/sending"
+)
+
+const problemContentType = "application/problem+json"
+
+var statusByCode = map[feed.ErrorCode]int{
+	feed.ErrCodeInvalidRequest:  http.StatusBadRequest,
+	feed.ErrCodeInvalidFileUrl:  http.StatusBadRequest,
+	feed.ErrCodeNotFound:        http.StatusNotFound,
+	feed.ErrCodeFileNotFound:    http.StatusNotFound,
+	feed.ErrCodeUnreadableFile:  http.StatusUnprocessableEntity,
+	feed.ErrCodeFileTooLarge:    http.StatusRequestEntityTooLarge,
+	feed.ErrCodeUnsupportedFile: http.StatusUnsupportedMediaType,
+	feed.ErrCodeUnknownFeed:     http.StatusConflict,
+	feed.ErrCodeRejected:        http.StatusBadGateway,
+	feed.ErrCodeUnavailable:     http.StatusServiceUnavailable,
+	feed.ErrCodeInternal:        http.StatusInternalServerError,
+}
+
+// Problem is the JSON document describing a failed request
+type Problem struct {
+	Code         feed.ErrorCode     `json:"code"`
+	Message      string             `json:"message"`
+	Status       int                `json:"status"`
+	UnknownItems []feed.UnknownItem `json:"unknownItems,omitempty"`
+}
+
+func NewProblem(err error) Problem {
+	code := feed.Code(err)
+	status, ok := statusByCode[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	p := Problem{Code: code, Message: err.Error(), Status: status}
+
+	var unknown *sending.UnknownFeedError
+	if errors.As(err, &unknown) {
+		p.UnknownItems = unknown.UnknownItems
+	}
+	return p
+}
+
+func writeProblem(w http.ResponseWriter, err error) {
+	p := NewProblem(err)
+
+	w.Header().Set("content-type", problemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
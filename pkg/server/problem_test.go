@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/cloud"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProblem(t *testing.T) {
+	unknownItems := []feed.UnknownItem{{Item: []string{"2", "Title"}, ItemError: "Feed does not have passage"}}
+
+	cases := []struct {
+		name   string
+		err    error
+		code   feed.ErrorCode
+		status int
+	}{
+		{"malformed drive url", cloud.ErrNotFoundGoogleDriveFileId("https://docs.google.com"), feed.ErrCodeInvalidFileUrl, http.StatusBadRequest},
+		{"unreadable document", devom.ErrReadingResource(errors.New("zip: not a valid zip file")), feed.ErrCodeUnreadableFile, http.StatusUnprocessableEntity},
+		{"devom rejects a call", devom.ErrCreatingResource(http.StatusCreated, http.StatusBadRequest), feed.ErrCodeRejected, http.StatusBadGateway},
+		{"devom fails", devom.ErrGettingResource(http.StatusOK, http.StatusInternalServerError), feed.ErrCodeUnavailable, http.StatusServiceUnavailable},
+		{"wrapped classified error", fmt.Errorf("importing: %w", sending.ErrUnknownFeed), feed.ErrCodeUnknownFeed, http.StatusConflict},
+		{"unclassified error", errors.New("boom"), feed.ErrCodeInternal, http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := server.NewProblem(c.err)
+
+			assert.Equal(t, c.code, p.Code)
+			assert.Equal(t, c.status, p.Status)
+			assert.Equal(t, c.err.Error(), p.Message)
+		})
+	}
+
+	t.Run("unknown feed lists the unknown items", func(t *testing.T) {
+		p := server.NewProblem(&sending.UnknownFeedError{UnknownItems: unknownItems})
+
+		assert.Equal(t, feed.ErrCodeUnknownFeed, p.Code)
+		assert.Equal(t, http.StatusConflict, p.Status)
+		assert.Equal(t, unknownItems, p.UnknownItems)
+	})
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+// recoverer answers a panicking request with an internal error instead of
+// letting it take the server down
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			log.Printf("[%s] 😱 %s\npanic: %v\n%s", r.Method, r.URL, v, debug.Stack())
+			writeProblem(w, feed.ErrPanic(v))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
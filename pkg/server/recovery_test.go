@@ -0,0 +1,47 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicFeeder struct{}
+
+func (pf panicFeeder) Feeds(ctx context.Context, req feeding.FeedReq) (*feed.ParsedItems, error) {
+	panic("bad document")
+}
+
+func TestServer_Recovers(t *testing.T) {
+	ps := sending.NewService(nil, nil)
+	df := panicFeeder{}
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
+
+	ds := newFeederServer(devotionals, ps, df, js)
+
+	t.Run("it answers an internal error on panic", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, payload))
+
+		assert.Equal(t, http.StatusInternalServerError, response.Code)
+		assert.Equal(t, feed.ErrCodeInternal, getProblemFromResponse(t, response.Body).Code)
+	})
+
+	t.Run("it fails a panicking job", func(t *testing.T) {
+		response := httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostImportFeedRequest(devotionals, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.Equal(t, feed.ErrCodeInternal, job.Code)
+	})
+}
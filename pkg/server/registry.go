@@ -0,0 +1,61 @@
+package server
+
+import (
+	"sort"
+	"sync"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+)
+
+// Feeds are the services of a kind of feeds, the sender and the feeder are built for each request
+// as they keep its destination
+type Feeds struct {
+	Sender sending.Factory
+	Feeder feeding.Factory
+	Jobs   jobs.Service
+	// Folders imports whole folders, it is optional
+	Folders batch.Service
+}
+
+// Registry binds each kind of feeds, as devotionals or topics, to its services
+type Registry struct {
+	mu    sync.RWMutex
+	kinds map[string]Feeds
+}
+
+func NewRegistry() *Registry {
+	return &Registry{kinds: make(map[string]Feeds)}
+}
+
+// Register binds the kind to the feeds, replacing the previous ones
+func (r *Registry) Register(kind string, f Feeds) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.kinds[kind] = f
+}
+
+func (r *Registry) Feeds(kind string) (Feeds, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.kinds[kind]
+	if !ok {
+		return Feeds{}, feed.ErrUnknownKind
+	}
+	return f, nil
+}
+
+// Kinds returns the registered kinds sorted by name
+func (r *Registry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.kinds))
+	for kind := range r.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
@@ -2,65 +2,232 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
 	"github.com/amelendres/go-feeder/pkg/feeding"
-	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/upload"
 	"github.com/gorilla/mux"
 )
 
 type FeederServer struct {
-	sender sending.Service
-	feeder feeding.Service
+	registry *Registry
+	uploads  *upload.FileProvider
+	sync     Syncer
+	// legacyKind serves the routes without kind
+	legacyKind string
 	http.Handler
 }
 
+type Option func(*FeederServer)
+
+// WithLegacyKind serves /feeds/import, /feeds/plan and /feeds/parse, the routes before the kinds,
+// as aliases of the routes of the kind
+func WithLegacyKind(kind string) Option {
+	return func(ds *FeederServer) {
+		ds.legacyKind = kind
+	}
+}
+
 const jsonContentType = "application/json"
 
-// NewFeederServer creates a DevServer with routing configured
-func NewFeederServer(
-	ss sending.Service,
-	fs feeding.Service,
-) *FeederServer {
-	ds := &FeederServer{sender: ss, feeder: fs}
+// NewFeederServer creates a FeederServer routing each kind of feeds to its registered services
+func NewFeederServer(reg *Registry, opts ...Option) *FeederServer {
+	ds := &FeederServer{registry: reg}
+	for _, opt := range opts {
+		opt(ds)
+	}
 
 	router := mux.NewRouter()
-	router.Handle("/feeds/import", http.HandlerFunc(ds.importFeedHandler))
-	router.Handle("/feeds/parse", http.HandlerFunc(ds.parseFeedHandler))
+	router.Handle("/feeds/{kind}/import", http.HandlerFunc(ds.planFeedHandler)).Queries("dryRun", "true")
+	router.Handle("/feeds/{kind}/import", http.HandlerFunc(ds.importFeedHandler))
+	router.Handle("/feeds/{kind}/folders/import", http.HandlerFunc(ds.planFolderHandler)).Queries("dryRun", "true")
+	router.Handle("/feeds/{kind}/folders/import", http.HandlerFunc(ds.importFolderHandler))
+	router.Handle("/feeds/{kind}/sync", http.HandlerFunc(ds.syncHandler)).Methods(http.MethodPost)
+	router.Handle("/feeds/{kind}/sync", http.HandlerFunc(ds.unsyncHandler)).Methods(http.MethodDelete)
+	router.Handle("/feeds/{kind}/plan", http.HandlerFunc(ds.planFeedHandler))
+	router.Handle("/feeds/{kind}/parse", http.HandlerFunc(ds.parseFeedHandler))
+	router.Handle("/jobs/{id}", http.HandlerFunc(ds.jobHandler)).Methods(http.MethodGet)
+	if ds.legacyKind != "" {
+		router.Handle("/feeds/{route:import|plan|parse}", alias(router, ds.legacyKind))
+	}
 
-	ds.Handler = router
+	ds.Handler = recoverer(router)
 
 	return ds
 }
 
+// alias serves the legacy route as the same route of the kind
+func alias(router http.Handler, kind string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.Clone(r.Context())
+		r.URL.Path = fmt.Sprintf("/feeds/%s/%s", kind, mux.Vars(r)["route"])
+		router.ServeHTTP(w, r)
+	})
+}
+
 func (ds *FeederServer) importFeedHandler(w http.ResponseWriter, r *http.Request) {
 
-	var req sending.SendReq
-	json.NewDecoder(r.Body).Decode(&req)
+	feeds, err := ds.registry.Feeds(mux.Vars(r)["kind"])
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
 
-	err := ds.sender.Send(req)
+	req, uploaded, err := ds.sendReq(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusConflict)
+		writeProblem(w, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusAccepted)
+	job, err := feeds.Jobs.Enqueue(req)
+	if err != nil {
+		ds.discard(uploaded)
+		writeProblem(w, err)
+		return
+	}
+
+	writeAccepted(w, job)
+}
+
+func (ds *FeederServer) planFeedHandler(w http.ResponseWriter, r *http.Request) {
+
+	feeds, err := ds.registry.Feeds(mux.Vars(r)["kind"])
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	req, uploaded, err := ds.sendReq(w, r)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	defer ds.discard(uploaded)
+
+	report, err := feeds.Sender().Plan(r.Context(), req)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(w).Encode(report)
 }
 
 func (ds *FeederServer) parseFeedHandler(w http.ResponseWriter, r *http.Request) {
 
-	var req feeding.FeedReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+	feeds, err := ds.registry.Feeds(mux.Vars(r)["kind"])
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	req, uploaded, err := ds.sendReq(w, r)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+	defer ds.discard(uploaded)
+
+	parsed, err := feeds.Feeder().Feeds(r.Context(), feeding.FeedReq(req))
+	if err != nil {
+		writeProblem(w, err)
 		return
 	}
 
-	feeds, err := ds.feeder.Feeds(req)
+	w.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(w).Encode(parsed)
+}
+
+// importFolderHandler enqueues the import of the folder documents as a job
+func (ds *FeederServer) importFolderHandler(w http.ResponseWriter, r *http.Request) {
+
+	feeds, req, err := ds.folderReq(r)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	job, err := feeds.Jobs.EnqueueFolder(req)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	writeAccepted(w, job)
+}
+
+// planFolderHandler plans every document of the folder, answering the aggregated report
+func (ds *FeederServer) planFolderHandler(w http.ResponseWriter, r *http.Request) {
+
+	feeds, req, err := ds.folderReq(r)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	report, err := feeds.Folders.Plan(r.Context(), req)
+	if err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (ds *FeederServer) folderReq(r *http.Request) (Feeds, batch.Req, error) {
+	var req batch.Req
+	feeds, err := ds.registry.Feeds(mux.Vars(r)["kind"])
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		return feeds, req, err
+	}
+	if feeds.Folders == nil {
+		return feeds, req, batch.ErrUnknownFolder
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return feeds, req, feed.NewError(feed.ErrCodeInvalidRequest, err)
+	}
+	return feeds, req, nil
+}
+
+func (ds *FeederServer) jobHandler(w http.ResponseWriter, r *http.Request) {
+
+	job, err := ds.job(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, err)
 		return
 	}
 
 	w.Header().Set("content-type", jsonContentType)
-	json.NewEncoder(w).Encode(feeds)
+	json.NewEncoder(w).Encode(job)
+}
+
+// job looks for the job in the services of every kind
+func (ds *FeederServer) job(id string) (*jobs.Job, error) {
+	for _, kind := range ds.registry.Kinds() {
+		feeds, err := ds.registry.Feeds(kind)
+		if err != nil {
+			continue
+		}
+		job, err := feeds.Jobs.Job(id)
+		if feed.Code(err) == feed.ErrCodeNotFound {
+			continue
+		}
+		return job, err
+	}
+	return nil, jobs.ErrJobNotFound
+}
+
+// writeAccepted answers the enqueued job, located by its url
+func writeAccepted(w http.ResponseWriter, job *jobs.Job) {
+	w.Header().Set("content-type", jsonContentType)
+	w.Header().Set("location", fmt.Sprintf("/jobs/%s", job.Id))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
 }
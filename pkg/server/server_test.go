@@ -6,17 +6,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/amelendres/go-feeder/internal/devom"
+	"github.com/amelendres/go-feeder/internal/devom/devomtest"
 	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
 	"github.com/amelendres/go-feeder/pkg/cloud"
 	"github.com/amelendres/go-feeder/pkg/feeding"
 	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/amelendres/go-feeder/pkg/jobs"
 	"github.com/amelendres/go-feeder/pkg/sending"
 	"github.com/amelendres/go-feeder/pkg/server"
 	"github.com/stretchr/testify/assert"
@@ -27,11 +34,10 @@ import (
 )
 
 var (
-	devomAPIUrl = os.Getenv("DEVOM_API_URL")
-	api         = *devom.NewAPI(devomAPIUrl)
-	feedSource  = map[string]string{
+	feedSource = map[string]string{
 		"dev-ok":              "../../internal/devom/_test_devotionals-ok.docx",
 		"dev-ko":              "../../internal/devom/_test_devotionals-ko.docx",
+		"dev-html":            "../../internal/devom/_test_devotionals-ok.html",
 		"no-file":             "../../internal/devom/_test_not-exists-file",
 		"drive-dev-2019a":     "https://docs.google.com/document/d/1XI0cxe6T1VSipeeCmEbk14VDkZM5PS_c/preview",
 		"drive-dev-bad-title": "https://docs.google.com/document/d/1frfbhH2oUVOHLK7aNWr-0-2--hemIccj/preview",
@@ -54,8 +60,35 @@ var (
 	}
 )
 
-// TODO: mock DEVOM server
+const (
+	devotionals = "devotionals"
+	topics      = "topics"
+)
+
+// newFeederServer serves a single kind of feeds
+func newFeederServer(kind string, ss sending.Service, fs feeding.Service, js jobs.Service) *server.FeederServer {
+	reg := server.NewRegistry()
+	reg.Register(kind, server.Feeds{Sender: sending.Shared(ss), Feeder: feeding.Shared(fs), Jobs: js})
+	return server.NewFeederServer(reg)
+}
+
+// newDevomAPI starts an in-memory devom API with the payload plans
+func newDevomAPI(t *testing.T) devom.API {
+	t.Helper()
+	srv := devomtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	for year, planId := range planIds {
+		if year < 2022 {
+			srv.SeedPlan(devom.Plan{Id: planId, Title: fmt.Sprint(year), AuthorId: payload.AuthorId})
+		}
+	}
+	srv.SeedYearlyPlan(2019, payload.AuthorId, 100)
+	return *devom.NewAPI(srv.URL)
+}
+
 func TestServer_ImportDevotionals_FromFS(t *testing.T) {
+	api := newDevomAPI(t)
 
 	fp := fs.NewFileProvider()
 	parser := devom.NewDevotionalParser(api)
@@ -64,15 +97,21 @@ func TestServer_ImportDevotionals_FromFS(t *testing.T) {
 
 	ps := sending.NewService(sender, feeder)
 	df := feeding.NewService(feeder)
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
 
-	ds := server.NewFeederServer(ps, df)
+	ds := newFeederServer(devotionals, ps, df, js)
 
 	t.Run("Unknown feeds", func(t *testing.T) {
 		payload.FileUrl = feedSource["dev-ko"]
 
 		response := httptest.NewRecorder()
-		ds.ServeHTTP(response, newPostImportFeedRequest(payload))
-		assert.Equal(t, http.StatusConflict, response.Code)
+		ds.ServeHTTP(response, newPostImportFeedRequest(devotionals, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.NotEmpty(t, job.Errors)
+		assert.Equal(t, feed.ErrCodeUnknownFeed, job.Code)
 	})
 
 	t.Run("Non existing resource file", func(t *testing.T) {
@@ -80,20 +119,30 @@ func TestServer_ImportDevotionals_FromFS(t *testing.T) {
 		payload.FileUrl = feedSource["no-file"]
 
 		response := httptest.NewRecorder()
-		ds.ServeHTTP(response, newPostImportFeedRequest(payload))
-		assert.Equal(t, http.StatusConflict, response.Code)
+		ds.ServeHTTP(response, newPostImportFeedRequest(devotionals, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.NotEmpty(t, job.Errors)
+		assert.Equal(t, feed.ErrCodeFileNotFound, job.Code)
 	})
 
 	t.Run("A new devotional feeds", func(t *testing.T) {
 		payload.FileUrl = feedSource["dev-ok"]
 
 		response := httptest.NewRecorder()
-		ds.ServeHTTP(response, newPostImportFeedRequest(payload))
+		ds.ServeHTTP(response, newPostImportFeedRequest(devotionals, payload))
 		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Empty(t, job.Errors)
 	})
 }
 
 func TestServer_ImportTopics(t *testing.T) {
+	api := newDevomAPI(t)
 
 	fp := fs.NewFileProvider()
 	parser := devom.NewTopicParser(api)
@@ -102,15 +151,21 @@ func TestServer_ImportTopics(t *testing.T) {
 
 	ps := sending.NewService(sender, feeder)
 	df := feeding.NewService(feeder)
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
 
-	ds := server.NewFeederServer(ps, df)
+	ds := newFeederServer(topics, ps, df, js)
 
 	t.Run("Unknown items", func(t *testing.T) {
 		payload.FileUrl = feedSource["topics-ko"]
 
 		response := httptest.NewRecorder()
-		ds.ServeHTTP(response, newPostImportFeedRequest(payload))
-		assert.Equal(t, http.StatusConflict, response.Code)
+		ds.ServeHTTP(response, newPostImportFeedRequest(topics, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.NotEmpty(t, job.Errors)
+		assert.Equal(t, feed.ErrCodeUnknownFeed, job.Code)
 	})
 
 	t.Run("Non existing resource file", func(t *testing.T) {
@@ -118,20 +173,320 @@ func TestServer_ImportTopics(t *testing.T) {
 		payload.FileUrl = feedSource["no-file"]
 
 		response := httptest.NewRecorder()
-		ds.ServeHTTP(response, newPostImportFeedRequest(payload))
-		assert.Equal(t, http.StatusConflict, response.Code)
+		ds.ServeHTTP(response, newPostImportFeedRequest(topics, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Failed, job.State)
+		assert.NotEmpty(t, job.Errors)
+		assert.Equal(t, feed.ErrCodeFileNotFound, job.Code)
 	})
 
 	t.Run("Valid feed items", func(t *testing.T) {
 		payload.FileUrl = feedSource["topics-ok"]
 
 		response := httptest.NewRecorder()
-		ds.ServeHTTP(response, newPostImportFeedRequest(payload))
+		ds.ServeHTTP(response, newPostImportFeedRequest(topics, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Empty(t, job.Errors)
+	})
+}
+
+func TestServer_Kinds(t *testing.T) {
+	api := newDevomAPI(t)
+
+	fp := fs.NewFileProvider()
+	store := jobs.NewMemoryStore()
+	reg := server.NewRegistry()
+	devFeeder := feed.NewFeeder(devom.NewDevotionalParser(api), []feed.FileProvider{fp})
+	devSender := sending.NewService(devom.NewDevotionalSender(api), devFeeder)
+	devFeeding := feeding.NewService(devFeeder)
+	reg.Register(devotionals, server.Feeds{
+		Sender: sending.Shared(devSender),
+		Feeder: feeding.Shared(devFeeding),
+		Jobs:   jobs.NewService(context.Background(), store, feeding.Shared(devFeeding), sending.Shared(devSender), 1),
+	})
+	topicFeeder := feed.NewFeeder(devom.NewTopicParser(api), []feed.FileProvider{fp})
+	topicSender := sending.NewService(devom.NewTopicSender(api), topicFeeder)
+	topicFeeding := feeding.NewService(topicFeeder)
+	reg.Register(topics, server.Feeds{
+		Sender: sending.Shared(topicSender),
+		Feeder: feeding.Shared(topicFeeding),
+		Jobs:   jobs.NewService(context.Background(), store, feeding.Shared(topicFeeding), sending.Shared(topicSender), 1),
+	})
+
+	ds := server.NewFeederServer(reg)
+
+	t.Run("it parses each kind with its parser", func(t *testing.T) {
+		payload.FileUrl = feedSource["dev-ok"]
+		response := httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, payload))
+		assert.Equal(t, 15, len(getParseFeedsFromResponse(t, response.Body).Items))
+
+		payload.FileUrl = feedSource["topics-ok"]
+		response = httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostParseFeedRequest(topics, payload))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, getParseFeedsFromResponse(t, response.Body).UnknownItems)
+	})
+
+	t.Run("it gets the jobs of every kind", func(t *testing.T) {
+		payload.FileUrl = feedSource["topics-ok"]
+
+		response := httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostImportFeedRequest(topics, payload))
+		assert.Equal(t, http.StatusAccepted, response.Code)
+
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Done, job.State)
+	})
+
+	t.Run("it does not find an unknown kind", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostParseFeedRequest("sermons", payload))
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Equal(t, feed.ErrCodeNotFound, getProblemFromResponse(t, response.Body).Code)
+	})
+
+	t.Run("it does not find an unknown job", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newGetJobRequest("does-not-exist"))
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("it parses with the field mapping of the request", func(t *testing.T) {
+		csv := filepath.Join(t.TempDir(), "devotionals.csv")
+		assert.Nil(t, ioutil.WriteFile(csv, []byte("Dia,Titulo,Pasaje,Texto\n1,EL PASAJE,“Porque de tal manera amó Dios al mundo” (Juan 3:16).,Un amor sin medida.\n"), 0644))
+		csvFeeding := feeding.NewService(feed.NewFeeder(devom.NewCSVDevotionalParser(api, devom.FieldMapping{}), []feed.FileProvider{fp}))
+		csvReg := server.NewRegistry()
+		csvReg.Register("csv-devotionals", server.Feeds{Feeder: feeding.Shared(csvFeeding)})
+		req := payload
+		req.FileUrl = csv
+
+		response := httptest.NewRecorder()
+		server.NewFeederServer(csvReg).ServeHTTP(response, newPostParseFeedRequest("csv-devotionals", req))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, getParseFeedsFromResponse(t, response.Body).Items)
+
+		req.FieldMap = "day=Dia,title=Titulo,passage_text=Pasaje,content=Texto"
+		response = httptest.NewRecorder()
+		server.NewFeederServer(csvReg).ServeHTTP(response, newPostParseFeedRequest("csv-devotionals", req))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "EL PASAJE", getParseFeedsFromResponse(t, response.Body).Items[0]["title"])
+	})
+
+	t.Run("it serves the routes without kind as the legacy kind", func(t *testing.T) {
+		legacy := server.NewFeederServer(reg, server.WithLegacyKind(devotionals))
+		payload.FileUrl = feedSource["dev-ok"]
+
+		response := httptest.NewRecorder()
+		legacy.ServeHTTP(response, newPostFeedRequest("/feeds/parse", payload))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, 15, len(getParseFeedsFromResponse(t, response.Body).Items))
+
+		response = httptest.NewRecorder()
+		legacy.ServeHTTP(response, newPostFeedRequest("/feeds/import?dryRun=true", payload))
+		assert.Equal(t, http.StatusOK, response.Code)
+		var report feed.Report
+		json.NewDecoder(response.Body).Decode(&report)
+		assert.Equal(t, 15, report.Count(feed.OutcomeCreated))
+
+		response = httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostFeedRequest("/feeds/parse", payload))
+		assert.Equal(t, http.StatusNotFound, response.Code)
+
+		payload.FileUrl = feedSource["topics-ok"]
+		response = httptest.NewRecorder()
+		server.NewFeederServer(reg, server.WithLegacyKind(topics)).ServeHTTP(response, newPostFeedRequest("/feeds/parse", payload))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Empty(t, getParseFeedsFromResponse(t, response.Body).UnknownItems)
+	})
+}
+
+// stubFolder lists local documents on the stub:// scheme
+type stubFolder struct {
+	feed.FileProvider
+	files []feed.FolderFile
+}
+
+func (sf stubFolder) Files(ctx context.Context, url string) ([]feed.FolderFile, error) {
+	return sf.files, nil
+}
+
+func (sf stubFolder) Name() string {
+	return "stub"
+}
+
+func TestServer_ConcurrentRequests(t *testing.T) {
+	srv := devomtest.NewServer()
+	defer srv.Close()
+	otherAuthorId := "0c8b8d6e-5a5f-4d3e-9f2b-6a1d0e7c3b21"
+	srv.SeedPlan(devom.Plan{Id: planIds[2020], Title: "2020", AuthorId: otherAuthorId})
+	srv.SeedPlan(devom.Plan{Id: planIds[2021], Title: "2021", AuthorId: payload.AuthorId})
+	api := *devom.NewAPI(srv.URL)
+
+	providers := []feed.FileProvider{fs.NewFileProvider()}
+	newFeeding := func() feeding.Service {
+		return feeding.NewService(feed.NewFeeder(devom.NewDevotionalParser(api), providers))
+	}
+	newSending := func() sending.Service {
+		return sending.NewService(devom.NewDevotionalSender(api), feed.NewFeeder(devom.NewDevotionalParser(api), providers))
+	}
+	reg := server.NewRegistry()
+	reg.Register(devotionals, server.Feeds{
+		Sender: newSending,
+		Feeder: newFeeding,
+		Jobs:   jobs.NewService(context.Background(), jobs.NewMemoryStore(), newFeeding, newSending, 2),
+	})
+	ds := server.NewFeederServer(reg)
+
+	t.Run("it imports to the plan of the job while planning and parsing another plan", func(t *testing.T) {
+		imported := payload
+		imported.PlanId = planIds[2021]
+		imported.FileUrl = feedSource["dev-ok"]
+		planned := imported
+		planned.PlanId = planIds[2020]
+		planned.AuthorId = otherAuthorId
+
+		var wg sync.WaitGroup
+		accepted := httptest.NewRecorder()
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			ds.ServeHTTP(accepted, newPostImportFeedRequest(devotionals, imported))
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				response := httptest.NewRecorder()
+				ds.ServeHTTP(response, newPostPlanFeedRequest(devotionals, planned))
+				assert.Equal(t, http.StatusOK, response.Code)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				response := httptest.NewRecorder()
+				ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, planned))
+				assert.Equal(t, http.StatusOK, response.Code)
+			}
+		}()
+		wg.Wait()
+
+		assert.Equal(t, http.StatusAccepted, accepted.Code)
+		job := waitJob(t, ds, getJobFromResponse(t, accepted.Body).Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Len(t, srv.DailyDevotionals(planIds[2021]), 15)
+		assert.Empty(t, srv.DailyDevotionals(planIds[2020]))
+	})
+}
+
+func TestServer_ImportFolder(t *testing.T) {
+	api := newDevomAPI(t)
+
+	fp := fs.NewFileProvider()
+	feeder := feed.NewFeeder(devom.NewDevotionalParser(api), []feed.FileProvider{fp})
+	ps := sending.NewService(devom.NewDevotionalSender(api), feeder)
+	folder := stubFolder{fp, []feed.FolderFile{
+		{Name: planIds[2021] + " Devotionals.docx", Url: feedSource["dev-ok"]},
+		{Name: planIds[2020] + " Devotionals.docx", Url: feedSource["dev-ko"]},
+		{Name: "cover.png", Url: "cover.png"},
+	}}
+
+	bs := batch.NewService(sending.Shared(ps), []feed.Folder{folder})
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), nil, sending.Shared(ps), 1, jobs.WithFolders(bs))
+
+	reg := server.NewRegistry()
+	reg.Register(devotionals, server.Feeds{Sender: sending.Shared(ps), Jobs: js, Folders: bs})
+	reg.Register(topics, server.Feeds{Sender: sending.Shared(ps)})
+	ds := server.NewFeederServer(reg)
+	req := batch.Req{FolderUrl: "stub://folder", AuthorId: payload.AuthorId, PublisherId: payload.PublisherId}
+
+	t.Run("it plans every document of the folder", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostFolderRequest(devotionals, "?dryRun=true", req))
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		report := getBatchReportFromResponse(t, response.Body)
+		assert.Len(t, report.Documents, 2)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, feed.ErrCodeUnknownFeed, report.Documents[1].Code)
+		assert.Equal(t, []string{"cover.png"}, report.Ignored)
+		assert.NotZero(t, report.Outcomes[feed.OutcomeCreated])
+	})
+
+	t.Run("it imports every document of the folder", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostFolderRequest(devotionals, "", req))
+
 		assert.Equal(t, http.StatusAccepted, response.Code)
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Equal(t, 1, job.Sent)
+		assert.Equal(t, 1, job.Failed)
+		report := job.Folder
+		assert.Equal(t, planIds[2021], report.Documents[0].PlanId)
+		assert.Empty(t, report.Documents[0].Error)
+		assert.Equal(t, report.Documents[0].Report.Count(feed.OutcomeCreated), report.Outcomes[feed.OutcomeCreated])
+	})
+
+	t.Run("it fails without folders", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostFolderRequest(topics, "", req))
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestServer_PlanDevotionals_FromFS(t *testing.T) {
+	api := newDevomAPI(t)
+
+	fp := fs.NewFileProvider()
+	parser := devom.NewDevotionalParser(api)
+	feeder := feed.NewFeeder(parser, []feed.FileProvider{fp})
+	sender := devom.NewDevotionalSender(api)
+
+	ps := sending.NewService(sender, feeder)
+	df := feeding.NewService(feeder)
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
+
+	ds := newFeederServer(devotionals, ps, df, js)
+
+	t.Run("Unknown feeds", func(t *testing.T) {
+		payload.FileUrl = feedSource["dev-ko"]
+
+		response := httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostPlanFeedRequest(devotionals, payload))
+		assert.Equal(t, http.StatusConflict, response.Code)
+		assert.Equal(t, 6, len(getProblemFromResponse(t, response.Body).UnknownItems))
+	})
+
+	t.Run("A new devotional feeds", func(t *testing.T) {
+		payload.FileUrl = feedSource["dev-ok"]
+
+		response := httptest.NewRecorder()
+		ds.ServeHTTP(response, newPostPlanFeedRequest(devotionals, payload))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var report feed.Report
+		json.NewDecoder(response.Body).Decode(&report)
+		assert.NotEmpty(t, report.Items)
+		assert.Zero(t, report.Count(feed.OutcomeFailed))
 	})
 }
 
 func TestServer_ParseDevotionals_FromFS(t *testing.T) {
+	api := newDevomAPI(t)
 
 	fp := fs.NewFileProvider()
 	parser := devom.NewDevotionalParser(api)
@@ -140,15 +495,16 @@ func TestServer_ParseDevotionals_FromFS(t *testing.T) {
 
 	ps := sending.NewService(sender, feeder)
 	df := feeding.NewService(feeder)
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
 
-	ds := server.NewFeederServer(ps, df)
+	ds := newFeederServer(devotionals, ps, df, js)
 
 	t.Run("A valid devotional feeds", func(t *testing.T) {
 		payload.FileUrl = feedSource["dev-ok"]
 
 		response := httptest.NewRecorder()
 
-		ds.ServeHTTP(response, newPostParseFeedRequest(payload))
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, payload))
 
 		parseFeeds := getParseFeedsFromResponse(t, response.Body)
 		assert.Equal(t, http.StatusOK, response.Code)
@@ -160,7 +516,7 @@ func TestServer_ParseDevotionals_FromFS(t *testing.T) {
 		payload.FileUrl = feedSource["dev-ko"]
 		response := httptest.NewRecorder()
 
-		ds.ServeHTTP(response, newPostParseFeedRequest(payload))
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, payload))
 
 		parseFeeds := getParseFeedsFromResponse(t, response.Body)
 		assert.Equal(t, http.StatusOK, response.Code)
@@ -172,17 +528,45 @@ func TestServer_ParseDevotionals_FromFS(t *testing.T) {
 		payload.FileUrl = feedSource["no-file"]
 		response := httptest.NewRecorder()
 
-		ds.ServeHTTP(response, newPostParseFeedRequest(payload))
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, payload))
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		assert.Equal(t, feed.ErrCodeFileNotFound, getProblemFromResponse(t, response.Body).Code)
+	})
+
+	t.Run("A devotional feeds in html", func(t *testing.T) {
+		req := payload
+		req.FileUrl = feedSource["dev-ok"]
+		req.Format = feed.FormatHTML
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, req))
+
+		parseFeeds := getParseFeedsFromResponse(t, response.Body)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, 15, len(parseFeeds.Items))
+		assert.Equal(t, "<p><em>“Lámpara es a mis pies tu palabra, y lumbrera a mi camino”</em></p>", parseFeeds.Items[0]["passage_text"])
+	})
+
+	t.Run("An unknown format", func(t *testing.T) {
+		req := payload
+		req.FileUrl = feedSource["dev-ok"]
+		req.Format = "rtf"
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, req))
 
 		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, feed.ErrCodeInvalidRequest, getProblemFromResponse(t, response.Body).Code)
 	})
 }
 
 func TestServer_ParseDevotionals_FromGoogleDrive(t *testing.T) {
+	api := newDevomAPI(t)
 
 	googleAPIKey := os.Getenv("GOOGLE_API_KEY")
 	if googleAPIKey == "" {
-		log.Fatal("ERROR: you must provide a Google Api Key")
+		t.Skip("you must provide a Google Api Key")
 	}
 	ctx := context.Background()
 	driveService, _ := drive.NewService(ctx, option.WithAPIKey(googleAPIKey))
@@ -194,15 +578,16 @@ func TestServer_ParseDevotionals_FromGoogleDrive(t *testing.T) {
 
 	ps := sending.NewService(sender, feeder)
 	df := feeding.NewService(feeder)
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
 
-	ds := server.NewFeederServer(ps, df)
+	ds := newFeederServer(devotionals, ps, df, js)
 
 	t.Run("it parses from Google Drive File on POST", func(t *testing.T) {
 		payload.FileUrl = feedSource["drive-dev-2019a"]
 
 		response := httptest.NewRecorder()
 
-		ds.ServeHTTP(response, newPostParseFeedRequest(payload))
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, payload))
 
 		parseFeeds := getParseFeedsFromResponse(t, response.Body)
 		assert.Equal(t, http.StatusOK, response.Code)
@@ -211,24 +596,109 @@ func TestServer_ParseDevotionals_FromGoogleDrive(t *testing.T) {
 	})
 }
 
-func newPostImportFeedRequest(sp sending.SendReq) *http.Request {
+func newPostFeedRequest(path string, sp sending.SendReq) *http.Request {
+	body, err := json.Marshal(sp)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+	return req
+}
+
+func newPostImportFeedRequest(kind string, sp sending.SendReq) *http.Request {
+	body, err := json.Marshal(sp)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/feeds/%s/import", kind), bytes.NewBuffer(body))
+	return req
+}
+
+func newPostPlanFeedRequest(kind string, sp sending.SendReq) *http.Request {
 	body, err := json.Marshal(sp)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	req, _ := http.NewRequest(http.MethodPost, "/feeds/import", bytes.NewBuffer(body))
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/feeds/%s/import?dryRun=true", kind), bytes.NewBuffer(body))
 	return req
 }
 
-func newPostParseFeedRequest(sp sending.SendReq) *http.Request {
+func newPostParseFeedRequest(kind string, sp sending.SendReq) *http.Request {
 	body, err := json.Marshal(sp)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	req, _ := http.NewRequest(http.MethodPost, "/feeds/parse", bytes.NewBuffer(body))
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/feeds/%s/parse", kind), bytes.NewBuffer(body))
+	return req
+}
+
+func newPostFolderRequest(kind, query string, br batch.Req) *http.Request {
+	body, err := json.Marshal(br)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/feeds/%s/folders/import%s", kind, query), bytes.NewBuffer(body))
 	return req
 }
 
+func newGetJobRequest(id string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/jobs/%s", id), nil)
+	return req
+}
+
+func waitJob(t *testing.T, ds *server.FeederServer, id string) jobs.Job {
+	t.Helper()
+	timeout := time.After(30 * time.Second)
+	for {
+		response := httptest.NewRecorder()
+		ds.ServeHTTP(response, newGetJobRequest(id))
+		if response.Code != http.StatusOK {
+			t.Fatalf("Unable to get job <%s>, status %d", id, response.Code)
+		}
+
+		job := getJobFromResponse(t, response.Body)
+		if job.State == jobs.Done || job.State == jobs.Failed {
+			return job
+		}
+
+		select {
+		case <-timeout:
+			t.Fatalf("Job <%s> not finished, state %s", id, job.State)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func getJobFromResponse(t *testing.T, body io.Reader) jobs.Job {
+	t.Helper()
+	var job jobs.Job
+	if err := json.NewDecoder(body).Decode(&job); err != nil {
+		t.Fatalf("Unable to parse response from server %q into Job, '%v'", body, err)
+	}
+
+	return job
+}
+
+func getBatchReportFromResponse(t *testing.T, body io.Reader) batch.Report {
+	t.Helper()
+	var report batch.Report
+	if err := json.NewDecoder(body).Decode(&report); err != nil {
+		t.Fatalf("Unable to parse response from server %q into Report, '%v'", body, err)
+	}
+
+	return report
+}
+
+func getProblemFromResponse(t *testing.T, body io.Reader) server.Problem {
+	t.Helper()
+	var problem server.Problem
+	if err := json.NewDecoder(body).Decode(&problem); err != nil {
+		t.Fatalf("Unable to parse response from server %q into Problem, '%v'", body, err)
+	}
+
+	return problem
+}
+
 func getParseFeedsFromResponse(t *testing.T, body io.Reader) feeder.ParsedItems {
 	t.Helper()
 	parsedFeeds, err := newParseFeedsFromJSON(body)
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/gorilla/mux"
+)
+
+var ErrSyncDisabled = feed.NewError(feed.ErrCodeInvalidRequest, errors.New("file sync is disabled"))
+
+// Syncer imports the files registered for each kind whenever their content changes
+type Syncer interface {
+	Register(ctx context.Context, kind string, req sending.SendReq) error
+	Unregister(kind, fileUrl string) error
+	// OnChange sets the import of the changed files, a file keeps changed until its import succeeds
+	OnChange(onChange func(ctx context.Context, kind string, req sending.SendReq) error)
+}
+
+// WithSync re-imports the registered files as upserts whenever they change
+func WithSync(s Syncer) Option {
+	return func(ds *FeederServer) {
+		ds.sync = s
+		s.OnChange(ds.importChanged)
+	}
+}
+
+// importChanged imports the changed file in a job of its kind, waiting for it,
+// so the syncer only records the imported versions
+func (ds *FeederServer) importChanged(ctx context.Context, kind string, req sending.SendReq) error {
+	feeds, err := ds.registry.Feeds(kind)
+	if err != nil {
+		return err
+	}
+	job, err := feeds.Jobs.Enqueue(req)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s changed, importing in job %s", req.FileUrl, job.Id)
+
+	job, err = jobs.Wait(ctx, feeds.Jobs, job.Id)
+	if err != nil {
+		return err
+	}
+	if job.State == jobs.Failed {
+		return feed.NewError(job.Code, fmt.Errorf("job %s failed: %s", job.Id, strings.Join(job.Errors, "; ")))
+	}
+	return nil
+}
+
+// syncHandler registers the file of the import request for the kind, each change imports it as an upsert
+func (ds *FeederServer) syncHandler(w http.ResponseWriter, r *http.Request) {
+
+	kind := mux.Vars(r)["kind"]
+	if _, err := ds.registry.Feeds(kind); err != nil {
+		writeProblem(w, err)
+		return
+	}
+	if ds.sync == nil {
+		writeProblem(w, ErrSyncDisabled)
+		return
+	}
+
+	var req sending.SendReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, feed.NewError(feed.ErrCodeInvalidRequest, err))
+		return
+	}
+	req.Upsert = true
+
+	if err := ds.sync.Register(r.Context(), kind, req); err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ds *FeederServer) unsyncHandler(w http.ResponseWriter, r *http.Request) {
+
+	kind := mux.Vars(r)["kind"]
+	if _, err := ds.registry.Feeds(kind); err != nil {
+		writeProblem(w, err)
+		return
+	}
+	if ds.sync == nil {
+		writeProblem(w, ErrSyncDisabled)
+		return
+	}
+
+	var req sending.SendReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, feed.NewError(feed.ErrCodeInvalidRequest, err))
+		return
+	}
+	if err := ds.sync.Unregister(kind, req.FileUrl); err != nil {
+		writeProblem(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/server"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSyncer keeps the request of each registered file and the import of the changes
+type stubSyncer struct {
+	reqs     map[string]sending.SendReq
+	onChange func(ctx context.Context, kind string, req sending.SendReq) error
+}
+
+func (ss *stubSyncer) Register(ctx context.Context, kind string, req sending.SendReq) error {
+	ss.reqs[kind+" "+req.FileUrl] = req
+	return nil
+}
+
+func (ss *stubSyncer) Unregister(kind, fileUrl string) error {
+	delete(ss.reqs, kind+" "+fileUrl)
+	return nil
+}
+
+func (ss *stubSyncer) OnChange(onChange func(ctx context.Context, kind string, req sending.SendReq) error) {
+	ss.onChange = onChange
+}
+
+// stubJobs records the enqueued requests, finishing their jobs in the state
+type stubJobs struct {
+	reqs  []sending.SendReq
+	state jobs.State
+}
+
+func (sj *stubJobs) Enqueue(req sending.SendReq) (*jobs.Job, error) {
+	sj.reqs = append(sj.reqs, req)
+	return &jobs.Job{Id: fmt.Sprint(len(sj.reqs)), State: jobs.Queued}, nil
+}
+
+func (sj *stubJobs) EnqueueFolder(req batch.Req) (*jobs.Job, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (sj *stubJobs) Job(id string) (*jobs.Job, error) {
+	job := &jobs.Job{Id: id, State: sj.state}
+	if sj.state == jobs.Failed {
+		job.Errors, job.Code = []string{"devom unavailable"}, feed.ErrCodeUnavailable
+	}
+	return job, nil
+}
+
+func TestServer_Sync(t *testing.T) {
+	syncer := &stubSyncer{reqs: map[string]sending.SendReq{}}
+	js := &stubJobs{state: jobs.Done}
+	reg := server.NewRegistry()
+	reg.Register(devotionals, server.Feeds{Jobs: js})
+	ds := server.NewFeederServer(reg, server.WithSync(syncer))
+	req := sending.SendReq{PlanId: planIds[2021], FileUrl: feedSource["drive-dev-2019a"]}
+	upsert := sending.SendReq{PlanId: req.PlanId, FileUrl: req.FileUrl, Upsert: true}
+
+	t.Run("it registers the file of the kind as upsert", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newSyncRequest(http.MethodPost, devotionals, req))
+
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		assert.Equal(t, map[string]sending.SendReq{devotionals + " " + req.FileUrl: upsert}, syncer.reqs)
+	})
+
+	t.Run("it imports the changed files in a job of their kind", func(t *testing.T) {
+		assert.Nil(t, syncer.onChange(context.Background(), devotionals, upsert))
+
+		assert.Equal(t, []sending.SendReq{upsert}, js.reqs)
+	})
+
+	t.Run("it fails the change when its job fails", func(t *testing.T) {
+		js.state = jobs.Failed
+
+		err := syncer.onChange(context.Background(), devotionals, upsert)
+
+		assert.Equal(t, feed.ErrCodeUnavailable, feed.Code(err))
+	})
+
+	t.Run("it unregisters the file", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newSyncRequest(http.MethodDelete, devotionals, req))
+
+		assert.Equal(t, http.StatusNoContent, response.Code)
+		assert.Empty(t, syncer.reqs)
+	})
+
+	t.Run("it fails when sync is disabled", func(t *testing.T) {
+		ds := server.NewFeederServer(reg)
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newSyncRequest(http.MethodPost, devotionals, req))
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Equal(t, feed.ErrCodeInvalidRequest, getProblemFromResponse(t, response.Body).Code)
+	})
+}
+
+func newSyncRequest(method, kind string, sp sending.SendReq) *http.Request {
+	body, _ := json.Marshal(sp)
+	req, _ := http.NewRequest(method, fmt.Sprintf("/feeds/%s/sync", kind), bytes.NewBuffer(body))
+	return req
+}
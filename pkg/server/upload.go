@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/upload"
+)
+
+// maxFieldSize limits the destination fields of a multipart request
+const maxFieldSize = 1 << 10
+
+var (
+	ErrUploadsDisabled = feed.NewError(feed.ErrCodeUnsupportedFile, errors.New("file uploads are disabled"))
+	ErrMissingUpload   = feed.NewError(feed.ErrCodeInvalidRequest, errors.New("missing the uploaded file"))
+	ErrManyUploads     = feed.NewError(feed.ErrCodeInvalidRequest, errors.New("upload a single file"))
+	ErrRequestTooLarge = func(maxSize int64) error {
+		return feed.NewError(feed.ErrCodeFileTooLarge, fmt.Errorf("request exceeds %d bytes", maxSize))
+	}
+)
+
+// WithUploads accepts multipart/form-data requests, with the document as the "file" part
+// and the destination as the planId, authorId, publisherId and upsert fields
+func WithUploads(up *upload.FileProvider) Option {
+	return func(ds *FeederServer) {
+		ds.uploads = up
+	}
+}
+
+// sendReq decodes the JSON request or the multipart/form-data one, with the url of the file uploaded
+// by the request, only its own upload is discarded as the JSON requests may name any upload url
+func (ds *FeederServer) sendReq(w http.ResponseWriter, r *http.Request) (req sending.SendReq, uploaded string, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("content-type"))
+	if mediaType != "multipart/form-data" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, "", feed.NewError(feed.ErrCodeInvalidRequest, err)
+		}
+		return req, "", nil
+	}
+	if ds.uploads == nil {
+		return req, "", ErrUploadsDisabled
+	}
+
+	req, err = ds.uploadReq(w, r)
+	if err != nil {
+		ds.discard(req.FileUrl)
+		return req, "", err
+	}
+	return req, req.FileUrl, nil
+}
+
+// uploadReq streams the uploaded file to the upload provider
+func (ds *FeederServer) uploadReq(w http.ResponseWriter, r *http.Request) (sending.SendReq, error) {
+	var req sending.SendReq
+	maxSize := ds.uploads.MaxSize() + 8*maxFieldSize
+	r.Body = &limitedBody{http.MaxBytesReader(w, r.Body, maxSize), 0, maxSize}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return req, invalidUpload(err)
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return req, invalidUpload(err)
+		}
+
+		if part.FormName() == "file" {
+			if req.FileUrl != "" {
+				return req, ErrManyUploads
+			}
+			if req.FileUrl, err = ds.uploads.Put(part, part.Header.Get("content-type")); err != nil {
+				return req, err
+			}
+			continue
+		}
+
+		value, err := ioutil.ReadAll(io.LimitReader(part, maxFieldSize))
+		if err != nil {
+			return req, invalidUpload(err)
+		}
+		switch part.FormName() {
+		case "planId":
+			req.PlanId = string(value)
+		case "authorId":
+			req.AuthorId = string(value)
+		case "publisherId":
+			req.PublisherId = string(value)
+		case "format":
+			req.Format = feed.Format(value)
+		case "fieldMap":
+			req.FieldMap = string(value)
+		case "upsert":
+			if req.Upsert, err = strconv.ParseBool(string(value)); err != nil {
+				return req, feed.NewError(feed.ErrCodeInvalidRequest, err)
+			}
+		}
+	}
+
+	if req.FileUrl == "" {
+		return req, ErrMissingUpload
+	}
+	return req, nil
+}
+
+// discard removes the file uploaded by a request
+func (ds *FeederServer) discard(uploaded string) {
+	if ds.uploads != nil && uploaded != "" {
+		ds.uploads.Remove(uploaded)
+	}
+}
+
+// invalidUpload classifies the multipart failures as an invalid request, but the too large one
+func invalidUpload(err error) error {
+	var e *feed.Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return feed.NewError(feed.ErrCodeInvalidRequest, err)
+}
+
+// limitedBody fails past the size limit with ErrRequestTooLarge, as the error of http.MaxBytesReader is untyped
+type limitedBody struct {
+	io.ReadCloser
+	read, maxSize int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.maxSize {
+		return n, ErrRequestTooLarge(b.maxSize)
+	}
+	return n, err
+}
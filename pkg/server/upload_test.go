@@ -0,0 +1,236 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/amelendres/go-feeder/internal/devom"
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/jobs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/server"
+	"github.com/amelendres/go-feeder/pkg/upload"
+	"github.com/stretchr/testify/assert"
+)
+
+const docxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+func TestServer_UploadDevotionals(t *testing.T) {
+	api := newDevomAPI(t)
+
+	dir := t.TempDir()
+	up := upload.NewFileProvider(dir, 1<<20)
+	parser := devom.NewDevotionalParser(api)
+	feeder := feed.NewFeeder(parser, []feed.FileProvider{up})
+	sender := devom.NewDevotionalSender(api)
+
+	ps := sending.NewService(sender, feeder)
+	df := feeding.NewService(feeder)
+	js := jobs.NewService(context.Background(), jobs.NewMemoryStore(), feeding.Shared(df), sending.Shared(ps), 1)
+
+	reg := server.NewRegistry()
+	reg.Register(devotionals, server.Feeds{Sender: sending.Shared(ps), Feeder: feeding.Shared(df), Jobs: js})
+	ds := server.NewFeederServer(reg, server.WithUploads(up))
+
+	docx, err := ioutil.ReadFile(feedSource["dev-ok"])
+	assert.Nil(t, err)
+	payload.PlanId = planIds[2021]
+
+	t.Run("it parses an uploaded document", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newUploadRequest(t, "parse", payload, docxContentType, docx))
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, 15, len(getParseFeedsFromResponse(t, response.Body).Items))
+		assertNoUploads(t, dir)
+	})
+
+	t.Run("it parses an uploaded html document", func(t *testing.T) {
+		page, err := ioutil.ReadFile(feedSource["dev-html"])
+		assert.Nil(t, err)
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newUploadRequest(t, "parse", payload, "text/html; charset=utf-8", page))
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, 3, len(getParseFeedsFromResponse(t, response.Body).Items))
+		assertNoUploads(t, dir)
+	})
+
+	t.Run("it imports an uploaded document", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newUploadRequest(t, "import", payload, docxContentType, docx))
+
+		assert.Equal(t, http.StatusAccepted, response.Code)
+		job := waitJob(t, ds, getJobFromResponse(t, response.Body).Id)
+		assert.Equal(t, jobs.Done, job.State)
+		assert.Equal(t, 15, job.Sent)
+		assertNoUploads(t, dir)
+	})
+
+	t.Run("it rejects a large document", func(t *testing.T) {
+		large := append(append([]byte{}, docx...), make([]byte, 1<<20)...)
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newUploadRequest(t, "parse", payload, docxContentType, large))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+		assert.Equal(t, feed.ErrCodeFileTooLarge, getProblemFromResponse(t, response.Body).Code)
+		assertNoUploads(t, dir)
+	})
+
+	t.Run("it rejects a request exceeding the size limit", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		mw := multipart.NewWriter(body)
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="file"; filename="devotionals.docx"`)
+		h.Set("Content-Type", docxContentType)
+		part, err := mw.CreatePart(h)
+		assert.Nil(t, err)
+		part.Write(docx)
+		part.Write(make([]byte, 1<<20-len(docx)))
+		mw.WriteField("notes", strings.Repeat("a", 1<<14))
+		mw.Close()
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/feeds/%s/parse", devotionals), body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+		assert.Equal(t, feed.ErrCodeFileTooLarge, getProblemFromResponse(t, response.Body).Code)
+		assertNoUploads(t, dir)
+	})
+
+	t.Run("it keeps the uploads named by a JSON request", func(t *testing.T) {
+		url, err := up.Put(bytes.NewReader(docx), docxContentType)
+		assert.Nil(t, err)
+		defer up.Remove(url)
+		sp := payload
+		sp.FileUrl = url
+		sp.Format = "bogus"
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newPostParseFeedRequest(devotionals, sp))
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		files, err := ioutil.ReadDir(dir)
+		assert.Nil(t, err)
+		assert.Len(t, files, 1)
+	})
+
+	t.Run("it rejects an unsupported document", func(t *testing.T) {
+		for contentType, content := range map[string][]byte{
+			"text/plain":    docx,
+			docxContentType: []byte("not a docx"),
+		} {
+			response := httptest.NewRecorder()
+
+			ds.ServeHTTP(response, newUploadRequest(t, "parse", payload, contentType, content))
+
+			assert.Equal(t, http.StatusUnsupportedMediaType, response.Code)
+			assert.Equal(t, feed.ErrCodeUnsupportedFile, getProblemFromResponse(t, response.Body).Code)
+		}
+		assertNoUploads(t, dir)
+	})
+
+	t.Run("it requires the document", func(t *testing.T) {
+		response := httptest.NewRecorder()
+
+		ds.ServeHTTP(response, newUploadRequest(t, "parse", payload, "", nil))
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestServer_UploadKinds(t *testing.T) {
+	api := newDevomAPI(t)
+	dir := t.TempDir()
+	up := upload.NewFileProvider(dir, 1<<20)
+	reg := server.NewRegistry()
+	for _, kind := range devom.Kinds {
+		df := feeding.NewService(feed.NewFeeder(kind.NewParser(api, devom.FieldMapping{}), []feed.FileProvider{up}))
+		reg.Register(kind.Name, server.Feeds{Feeder: feeding.Shared(df)})
+	}
+	ds := server.NewFeederServer(reg, server.WithUploads(up))
+	read := func(path string) []byte {
+		b, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		return b
+	}
+	payload.PlanId = planIds[2021]
+
+	for _, tt := range []struct {
+		kind, contentType string
+		file              []byte
+	}{
+		{devotionals, docxContentType, read(feedSource["dev-ok"])},
+		{"csv-devotionals", "text/csv", []byte("day,title,passage_text,content\n1,EL PASAJE,“Porque de tal manera amó Dios al mundo” (Juan 3:16).,Un amor sin medida.\n")},
+		{"json-devotionals", "application/json", []byte(`[{"day":"1","title":"EL PASAJE","passage_text":"“Porque de tal manera amó Dios al mundo” (Juan 3:16).","content":"Un amor sin medida."}]`)},
+		{"markdown-devotionals", "text/markdown", read("../../internal/devom/_test_devotionals-ok.md")},
+		{topics, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", read(feedSource["topics-ok"])},
+	} {
+		t.Run("it parses an uploaded "+tt.kind+" file", func(t *testing.T) {
+			response := httptest.NewRecorder()
+
+			ds.ServeHTTP(response, newKindUploadRequest(t, tt.kind, "parse", payload, tt.contentType, tt.file))
+
+			assert.Equal(t, http.StatusOK, response.Code)
+			parsed := getParseFeedsFromResponse(t, response.Body)
+			assert.NotEmpty(t, parsed.Items)
+			assert.Empty(t, parsed.UnknownItems)
+			assertNoUploads(t, dir)
+		})
+	}
+}
+
+// newUploadRequest posts the payload destination and the file, if any, as multipart/form-data
+func newUploadRequest(t *testing.T, action string, sp sending.SendReq, contentType string, file []byte) *http.Request {
+	t.Helper()
+	return newKindUploadRequest(t, devotionals, action, sp, contentType, file)
+}
+
+func newKindUploadRequest(t *testing.T, kind, action string, sp sending.SendReq, contentType string, file []byte) *http.Request {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("planId", sp.PlanId)
+	mw.WriteField("authorId", sp.AuthorId)
+	mw.WriteField("publisherId", sp.PublisherId)
+	if file != nil {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="file"; filename="devotionals.docx"`)
+		h.Set("Content-Type", contentType)
+		part, err := mw.CreatePart(h)
+		assert.Nil(t, err)
+		io.Copy(part, bytes.NewReader(file))
+	}
+	mw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/feeds/%s/%s", kind, action), body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func assertNoUploads(t *testing.T, dir string) {
+	t.Helper()
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return
+	}
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,183 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/google/uuid"
+)
+
+// Scheme prefixes the url of the uploaded files
+const Scheme = "upload://"
+
+var (
+	ErrFileTooLarge = func(maxSize int64) error {
+		return feed.NewError(feed.ErrCodeFileTooLarge, fmt.Errorf("file exceeds %d bytes", maxSize))
+	}
+	ErrUnsupportedFile = func(contentType string) error {
+		return feed.NewError(feed.ErrCodeUnsupportedFile, fmt.Errorf("unsupported file <%s>, want a .docx, .xlsx, .doc, .odt, .pdf, .rtf, .html, .csv, .json, .md or .txt", contentType))
+	}
+	ErrUploadNotFound = feed.NewError(feed.ErrCodeFileNotFound, errors.New("uploaded file not found"))
+)
+
+// contentTypes of the supported files with the check of their first bytes, the documents are checked
+// by their magic bytes and the csv, json and markdown exports are text
+var contentTypes = map[string]func(head []byte) bool{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": document,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       document,
+	"application/vnd.oasis.opendocument.text":                                 document,
+	"application/msword":       document,
+	"application/pdf":          document,
+	"application/rtf":          document,
+	"text/rtf":                 document,
+	"text/html":                document,
+	"application/zip":          document,
+	"text/csv":                 text,
+	"application/json":         text,
+	"text/markdown":            text,
+	"text/x-markdown":          text,
+	"text/plain":               text,
+	"application/octet-stream": documentOrText,
+}
+
+// FileProvider keeps the uploaded files in a directory until they are read once
+type FileProvider struct {
+	dir     string
+	maxSize int64
+}
+
+func NewFileProvider(dir string, maxSize int64) *FileProvider {
+	return &FileProvider{dir: dir, maxSize: maxSize}
+}
+
+func (fp *FileProvider) MaxSize() int64 {
+	return fp.maxSize
+}
+
+// Put stores the uploaded file and returns its url
+func (fp *FileProvider) Put(r io.Reader, contentType string) (string, error) {
+	check, ok := supported(contentType)
+	if !ok {
+		return "", ErrUnsupportedFile(contentType)
+	}
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(512); !check(head) {
+		return "", ErrUnsupportedFile(contentType)
+	}
+
+	if err := os.MkdirAll(fp.dir, 0700); err != nil {
+		return "", feed.NewError(feed.ErrCodeInternal, err)
+	}
+	id := uuid.New().String()
+	f, err := os.OpenFile(fp.path(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", feed.NewError(feed.ErrCodeInternal, err)
+	}
+	n, err := io.Copy(f, io.LimitReader(br, fp.maxSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > fp.maxSize {
+		err = ErrFileTooLarge(fp.maxSize)
+	}
+	if err != nil {
+		os.Remove(fp.path(id))
+		if feed.Code(err) == feed.ErrCodeInternal {
+			err = feed.NewError(feed.ErrCodeUnreadableFile, err)
+		}
+		return "", err
+	}
+	return Scheme + id, nil
+}
+
+// File returns the uploaded file and removes it
+func (fp *FileProvider) File(ctx context.Context, path string) (io.Reader, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	id, ok := fp.id(path)
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	b, err := ioutil.ReadFile(fp.path(id))
+	if os.IsNotExist(err) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, feed.NewError(feed.ErrCodeUnreadableFile, err)
+	}
+	os.Remove(fp.path(id))
+	return bytes.NewReader(b), nil
+}
+
+// Remove discards an uploaded file that will not be read
+func (fp *FileProvider) Remove(path string) {
+	if id, ok := fp.id(path); ok {
+		os.Remove(fp.path(id))
+	}
+}
+
+func (fp *FileProvider) Name() string {
+	return "upload"
+}
+
+func (fp *FileProvider) id(path string) (string, bool) {
+	id := strings.TrimPrefix(path, Scheme)
+	if id == path || id == "" || filepath.Base(id) != id {
+		return "", false
+	}
+	return id, true
+}
+
+func (fp *FileProvider) path(id string) string {
+	return filepath.Join(fp.dir, id)
+}
+
+// supported returns the check of the content type, the files without one are documents or text
+func supported(contentType string) (func(head []byte) bool, bool) {
+	if contentType == "" {
+		return documentOrText, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	check, ok := contentTypes[mediaType]
+	return check, ok
+}
+
+// document checks the magic bytes of the supported files, html is sniffed
+func document(head []byte) bool {
+	_, ok := feed.DetectDocument(head)
+	return ok
+}
+
+// text checks the head is UTF-8 text without NUL bytes, it may end with a rune cut by the head
+func text(head []byte) bool {
+	if len(head) == 0 || bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+	for len(head) > 0 {
+		r, size := utf8.DecodeRune(head)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(head)
+		}
+		head = head[size:]
+	}
+	return true
+}
+
+func documentOrText(head []byte) bool {
+	return document(head) || text(head)
+}
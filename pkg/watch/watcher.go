@@ -0,0 +1,267 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+)
+
+// ReportSuffix names the report written next to each imported document
+const ReportSuffix = ".report.json"
+
+const (
+	defaultInterval = 5 * time.Second
+	defaultDebounce = 10 * time.Second
+)
+
+var ErrMissingPlan = feed.NewError(feed.ErrCodeInvalidRequest, errors.New("the document name does not start with a plan id and there is no default plan"))
+
+// Report is the outcome of importing a version of a document, identified by its checksum
+type Report struct {
+	File         string             `json:"file"`
+	Checksum     string             `json:"checksum"`
+	PlanId       string             `json:"planId"`
+	ImportedAt   time.Time          `json:"importedAt"`
+	Report       *feed.Report       `json:"report,omitempty"`
+	UnknownItems []feed.UnknownItem `json:"unknownItems,omitempty"`
+	Error        string             `json:"error,omitempty"`
+	Code         feed.ErrorCode     `json:"code,omitempty"`
+}
+
+type Option func(*Watcher)
+
+// WithInterval polls the directory every d
+func WithInterval(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// WithDebounce waits for a document to stay unchanged during d before importing it
+func WithDebounce(d time.Duration) Option {
+	return func(w *Watcher) {
+		w.debounce = d
+	}
+}
+
+// Watcher imports the new and changed documents of a directory
+type Watcher struct {
+	req      batch.Req
+	feeder   feeding.Service
+	sender   sending.Service
+	folder   feed.Folder
+	interval time.Duration
+	debounce time.Duration
+
+	mu    sync.Mutex
+	files map[string]*file
+}
+
+// file is the last seen version of a document
+type file struct {
+	size     int64
+	modTime  time.Time
+	seenAt   time.Time
+	imported bool
+}
+
+// NewWatcher watches the req folder, a directory or a glob pattern as "manuscripts/*.docx",
+// importing each document to the plan its name starts with or to the req one
+func NewWatcher(req batch.Req, f feeding.Service, s sending.Service, opts ...Option) *Watcher {
+	w := &Watcher{
+		req:      req,
+		feeder:   f,
+		sender:   s,
+		folder:   fs.NewFolder(),
+		interval: defaultInterval,
+		debounce: defaultDebounce,
+		files:    make(map[string]*file),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Run polls the directory until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("watching %s: %v", w.req.FolderUrl, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll imports the documents unchanged since the debounce period, returning their reports
+func (w *Watcher) Poll(ctx context.Context) ([]Report, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files, err := w.folder.Files(ctx, w.req.FolderUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []Report{}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ReportSuffix) || !w.settled(f.Url) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return reports, err
+		}
+		report, ok := w.load(ctx, f)
+		if !ok {
+			continue
+		}
+		if report.Code == feed.ErrCodeUnsupportedFile {
+			log.Printf("skipped %s: %s", f.Name, report.Error)
+			continue
+		}
+		if report.retried() {
+			// retries after the debounce period
+			delete(w.files, f.Url)
+		}
+		if err := writeReport(f.Url, report); err != nil {
+			return reports, err
+		}
+		log.Printf("imported %s: %s", f.Name, report)
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// settled tells whether the document stayed unchanged during the debounce period since its last import,
+// the entries of a zip archive settle with it
+func (w *Watcher) settled(url string) bool {
+	path, _, _ := fs.SplitZipEntry(url)
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	f, ok := w.files[url]
+	if !ok || f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+		f = &file{size: info.Size(), modTime: info.ModTime(), seenAt: time.Now()}
+		w.files[url] = f
+	}
+	return !f.imported && time.Now().Sub(f.seenAt) >= w.debounce
+}
+
+// load imports the document unless its previous report has the same checksum, the feeder one, and plan
+func (w *Watcher) load(ctx context.Context, f feed.FolderFile) (*Report, bool) {
+	w.files[f.Url].imported = true
+	report := &Report{File: f.Name, PlanId: batch.PlanId(f.Name), ImportedAt: time.Now()}
+	if report.PlanId == "" {
+		report.PlanId = w.req.PlanId
+	}
+	if report.PlanId == "" {
+		return report.fail(ErrMissingPlan), true
+	}
+
+	req := sending.SendReq{
+		PlanId:      report.PlanId,
+		AuthorId:    w.req.AuthorId,
+		PublisherId: w.req.PublisherId,
+		FileUrl:     f.Url,
+		Upsert:      w.req.Upsert,
+		Format:      w.req.Format,
+		FieldMap:    w.req.FieldMap,
+	}
+	feeds, err := w.feeder.Feeds(ctx, feeding.FeedReq(req))
+	if err != nil {
+		return report.fail(err), true
+	}
+	report.Checksum = feeds.Checksum
+	if previous, err := readReport(f.Url); err == nil && previous.unchanged(report) {
+		return nil, false
+	}
+	if len(feeds.UnknownItems) > 0 {
+		report.UnknownItems = feeds.UnknownItems
+		return report.fail(sending.ErrUnknownFeed), true
+	}
+
+	report.Report, err = w.sender.SendItems(ctx, req, feeds)
+	if err != nil {
+		return report.fail(err), true
+	}
+	return report, true
+}
+
+// unchanged tells whether the previous report imported the same content to the same plan,
+// retrying the imports failed by other causes than the document
+func (r *Report) unchanged(next *Report) bool {
+	return r.Checksum == next.Checksum && r.PlanId == next.PlanId && !r.retried()
+}
+
+// retried tells whether the import failed by other causes than the document, as an unavailable
+// or rejecting destination, an unknown plan or an unreadable file, so it is retried
+func (r *Report) retried() bool {
+	switch r.Code {
+	case "", feed.ErrCodeInvalidRequest, feed.ErrCodeUnknownFeed, feed.ErrCodeUnsupportedFile, feed.ErrCodeFileTooLarge:
+		return false
+	}
+	return true
+}
+
+func (r *Report) fail(err error) *Report {
+	r.Error = err.Error()
+	r.Code = feed.Code(err)
+	return r
+}
+
+func (r *Report) String() string {
+	if r.Error != "" {
+		return fmt.Sprintf("%s: %s", r.Code, r.Error)
+	}
+	return fmt.Sprintf("%d items, %d failed", len(r.Report.Items), r.Report.Count(feed.OutcomeFailed))
+}
+
+// reportPath is next to the document, the one of a zip entry is next to its archive, as "2021.zip!january.docx.report.json"
+func reportPath(url string) string {
+	path, entry, ok := fs.SplitZipEntry(url)
+	if ok {
+		path += "!" + strings.ReplaceAll(entry, "/", "!")
+	}
+	return filepath.Clean(path) + ReportSuffix
+}
+
+func readReport(path string) (*Report, error) {
+	b, err := ioutil.ReadFile(reportPath(path))
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func writeReport(path string, r *Report) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(reportPath(path), b, 0644)
+}
@@ -0,0 +1,235 @@
+package watch_test
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/batch"
+	"github.com/amelendres/go-feeder/pkg/feeding"
+	"github.com/amelendres/go-feeder/pkg/fs"
+	"github.com/amelendres/go-feeder/pkg/sending"
+	"github.com/amelendres/go-feeder/pkg/watch"
+	"github.com/stretchr/testify/assert"
+)
+
+const planId = "23a63256-f264-4d94-b7ed-8ce60f744ae3"
+
+// stubParser parses the content as an item, unknown when it is "?", failing when it is not a document as "!"
+type stubParser struct{}
+
+func (sp stubParser) Parse(ctx context.Context, r io.Reader) (*feed.ParsedItems, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch string(b) {
+	case "?":
+		return &feed.ParsedItems{UnknownItems: []feed.UnknownItem{{Item: []string{"?"}}}}, nil
+	case "!":
+		return nil, feed.NewError(feed.ErrCodeUnsupportedFile, errors.New("not a document"))
+	}
+	return &feed.ParsedItems{Items: []feed.Item{{"content": string(b)}}}, nil
+}
+
+func (sp stubParser) Destination(d *feed.Destination) {}
+
+// newFeeder parses the local documents with the stub parser
+func newFeeder() feeding.Service {
+	return feeding.NewService(feed.NewFeeder(stubParser{}, []feed.FileProvider{fs.NewFileProvider()}))
+}
+
+// stubSender records the sent items, failing with err while set
+type stubSender struct {
+	err  error
+	sent []feed.Item
+	reqs []sending.SendReq
+}
+
+func (ss *stubSender) Send(ctx context.Context, req sending.SendReq) (*feed.Report, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (ss *stubSender) SendItems(ctx context.Context, req sending.SendReq, feeds *feed.ParsedItems) (*feed.Report, error) {
+	if ss.err != nil {
+		return nil, ss.err
+	}
+	ss.reqs = append(ss.reqs, req)
+	report := feed.NewReport()
+	for _, item := range feeds.Items {
+		ss.sent = append(ss.sent, item)
+		report.Add(item, feed.OutcomeCreated, nil, nil)
+	}
+	return report, nil
+}
+
+func (ss *stubSender) Plan(ctx context.Context, req sending.SendReq) (*feed.Report, error) {
+	return nil, errors.New("not implemented")
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		assert.Nil(t, err)
+		w.Write([]byte(content))
+	}
+	assert.Nil(t, zw.Close())
+}
+
+func readReport(t *testing.T, path string) watch.Report {
+	t.Helper()
+	b, err := ioutil.ReadFile(path + watch.ReportSuffix)
+	assert.Nil(t, err)
+	var r watch.Report
+	assert.Nil(t, json.Unmarshal(b, &r))
+	return r
+}
+
+func TestWatcher_Poll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it imports the new and changed documents once", func(t *testing.T) {
+		dir := t.TempDir()
+		doc := filepath.Join(dir, planId+" 2021.docx")
+		write(t, doc, "january")
+		ss := &stubSender{}
+		w := watch.NewWatcher(batch.Req{FolderUrl: dir, AuthorId: "author"}, newFeeder(), ss, watch.WithDebounce(0))
+
+		reports, err := w.Poll(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, reports, 1)
+		assert.Equal(t, planId, ss.reqs[0].PlanId)
+		assert.Equal(t, "author", ss.reqs[0].AuthorId)
+		report := readReport(t, doc)
+		assert.Equal(t, 1, report.Report.Count(feed.OutcomeCreated))
+		assert.NotEmpty(t, report.Checksum)
+
+		reports, _ = w.Poll(ctx)
+		assert.Empty(t, reports)
+
+		write(t, doc, "january and february")
+		os.Chtimes(doc, time.Now(), time.Now().Add(time.Second))
+		reports, _ = w.Poll(ctx)
+		assert.Len(t, reports, 1)
+		assert.Equal(t, []feed.Item{{"content": "january"}, {"content": "january and february"}}, ss.sent)
+	})
+
+	t.Run("it does not import identical content again", func(t *testing.T) {
+		dir := t.TempDir()
+		doc := filepath.Join(dir, "2021.docx")
+		write(t, doc, "january")
+		req := batch.Req{FolderUrl: dir, PlanId: planId}
+
+		ss := &stubSender{}
+		watch.NewWatcher(req, newFeeder(), ss, watch.WithDebounce(0)).Poll(ctx)
+		os.Chtimes(doc, time.Now(), time.Now().Add(time.Second))
+		reports, err := watch.NewWatcher(req, newFeeder(), ss, watch.WithDebounce(0)).Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, reports)
+		assert.Len(t, ss.sent, 1)
+	})
+
+	t.Run("it waits for the document to settle", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, planId+".docx"), "january")
+		ss := &stubSender{}
+		w := watch.NewWatcher(batch.Req{FolderUrl: dir}, newFeeder(), ss, watch.WithDebounce(time.Hour))
+
+		reports, err := w.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, reports)
+		assert.Empty(t, ss.sent)
+	})
+
+	t.Run("it does not send documents with unknown items", func(t *testing.T) {
+		dir := t.TempDir()
+		doc := filepath.Join(dir, planId+".docx")
+		write(t, doc, "?")
+		ss := &stubSender{}
+
+		reports, err := watch.NewWatcher(batch.Req{FolderUrl: dir}, newFeeder(), ss, watch.WithDebounce(0)).Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, feed.ErrCodeUnknownFeed, reports[0].Code)
+		assert.Len(t, readReport(t, doc).UnknownItems, 1)
+		assert.Empty(t, ss.sent)
+	})
+
+	t.Run("it retries the imports failed by other causes than the document", func(t *testing.T) {
+		for _, code := range []feed.ErrorCode{feed.ErrCodeUnavailable, feed.ErrCodeRejected, feed.ErrCodeNotFound} {
+			dir := t.TempDir()
+			write(t, filepath.Join(dir, planId+".docx"), "january")
+			ss := &stubSender{err: feed.NewError(code, errors.New("devom failed"))}
+			w := watch.NewWatcher(batch.Req{FolderUrl: dir}, newFeeder(), ss, watch.WithDebounce(0))
+
+			reports, _ := w.Poll(ctx)
+			assert.Equal(t, code, reports[0].Code)
+
+			ss.err = nil
+			reports, _ = w.Poll(ctx)
+			assert.Len(t, reports, 1)
+			assert.Empty(t, reports[0].Error)
+			assert.Len(t, ss.sent, 1)
+		}
+	})
+
+	t.Run("it imports the entries of a zip archive", func(t *testing.T) {
+		dir := t.TempDir()
+		archive := filepath.Join(dir, "2021.zip")
+		writeZip(t, archive, map[string]string{planId + ".docx": "january"})
+		ss := &stubSender{}
+		w := watch.NewWatcher(batch.Req{FolderUrl: archive}, newFeeder(), ss, watch.WithDebounce(0))
+
+		reports, err := w.Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Len(t, reports, 1)
+		assert.Equal(t, []feed.Item{{"content": "january"}}, ss.sent)
+		assert.FileExists(t, archive+"!"+planId+".docx"+watch.ReportSuffix)
+		reports, _ = w.Poll(ctx)
+		assert.Empty(t, reports)
+	})
+
+	t.Run("it skips the files that are not documents", func(t *testing.T) {
+		dir := t.TempDir()
+		notes := filepath.Join(dir, planId+".txt")
+		write(t, notes, "!")
+
+		reports, err := watch.NewWatcher(batch.Req{FolderUrl: dir}, newFeeder(), &stubSender{}, watch.WithDebounce(0)).Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Empty(t, reports)
+		assert.NoFileExists(t, notes+watch.ReportSuffix)
+	})
+
+	t.Run("it reports the documents without plan", func(t *testing.T) {
+		dir := t.TempDir()
+		doc := filepath.Join(dir, "2021.docx")
+		write(t, doc, "january")
+
+		reports, err := watch.NewWatcher(batch.Req{FolderUrl: dir}, newFeeder(), &stubSender{}, watch.WithDebounce(0)).Poll(ctx)
+
+		assert.Nil(t, err)
+		assert.Equal(t, watch.ErrMissingPlan.Error(), reports[0].Error)
+	})
+}
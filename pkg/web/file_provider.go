@@ -0,0 +1,255 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultMaxRedirects = 5
+	defaultMaxSize      = 20 << 20
+)
+
+var (
+	ErrTooManyRedirects = func(max int) error {
+		return feed.NewError(feed.ErrCodeInvalidFileUrl, fmt.Errorf("stopped after %d redirects", max))
+	}
+	ErrFileTooLarge = func(maxSize int64) error {
+		return feed.NewError(feed.ErrCodeFileTooLarge, fmt.Errorf("file exceeds %d bytes", maxSize))
+	}
+	ErrDownloadingFile = func(url string, status int) error {
+		err := fmt.Errorf("fails downloading <%s>, unexpected response status %d", url, status)
+		switch {
+		case status == http.StatusNotFound || status == http.StatusGone:
+			return feed.NewError(feed.ErrCodeFileNotFound, err)
+		case status >= 500 || status == http.StatusTooManyRequests:
+			return feed.NewError(feed.ErrCodeUnavailable, err)
+		}
+		return feed.NewError(feed.ErrCodeUnreadableFile, err)
+	}
+	ErrRequestingFile = func(err error) error {
+		return feed.NewError(feed.ErrCodeUnavailable, err)
+	}
+)
+
+type Option func(*FileProvider)
+
+// WithHTTPClient downloads with the client, its timeout and redirect policy are replaced,
+// and its dialer when its transport is an http.Transport
+func WithHTTPClient(c *http.Client) Option {
+	return func(fp *FileProvider) {
+		fp.client = c
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(fp *FileProvider) {
+		fp.timeout = d
+	}
+}
+
+func WithMaxRedirects(max int) Option {
+	return func(fp *FileProvider) {
+		fp.maxRedirects = max
+	}
+}
+
+func WithMaxSize(bytes int64) Option {
+	return func(fp *FileProvider) {
+		fp.maxSize = bytes
+	}
+}
+
+// WithAllowedHosts downloads only from the hosts, and follows only the redirects to them
+func WithAllowedHosts(hosts ...string) Option {
+	return func(fp *FileProvider) {
+		for _, host := range hosts {
+			fp.allowedHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// WithPrivateAddresses downloads from the loopback and private addresses too, as the intranet servers,
+// the link-local and reserved ones stay refused
+func WithPrivateAddresses() Option {
+	return func(fp *FileProvider) {
+		fp.private = true
+	}
+}
+
+// WithHeader sends the header, as an Authorization one, only to the host
+func WithHeader(host, name, value string) Option {
+	return func(fp *FileProvider) {
+		host = strings.ToLower(host)
+		if fp.headers[host] == nil {
+			fp.headers[host] = http.Header{}
+		}
+		fp.headers[host].Set(name, value)
+	}
+}
+
+// HeaderOptions parses the host headers of the spec, as
+// "cms.example.org Authorization=Bearer xyz;cloud.example.org X-Api-Key=abc"
+func HeaderOptions(spec string) ([]Option, error) {
+	var opts []Option
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		hostHeader := strings.SplitN(entry, " ", 2)
+		if len(hostHeader) != 2 {
+			return nil, fmt.Errorf("invalid host header <%s>", entry)
+		}
+		header := strings.SplitN(strings.TrimSpace(hostHeader[1]), "=", 2)
+		if len(header) != 2 || header[0] == "" {
+			return nil, fmt.Errorf("invalid host header <%s>", entry)
+		}
+		opts = append(opts, WithHeader(hostHeader[0], header[0], header[1]))
+	}
+	return opts, nil
+}
+
+// FileProvider downloads the files of http and https urls, only from public addresses by default
+type FileProvider struct {
+	client       *http.Client
+	timeout      time.Duration
+	maxRedirects int
+	maxSize      int64
+	headers      map[string]http.Header
+	allowedHosts map[string]bool
+	private      bool
+}
+
+func NewFileProvider(opts ...Option) feed.FileProvider {
+	fp := &FileProvider{
+		client:       &http.Client{},
+		timeout:      defaultTimeout,
+		maxRedirects: defaultMaxRedirects,
+		maxSize:      defaultMaxSize,
+		headers:      make(map[string]http.Header),
+		allowedHosts: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(fp)
+	}
+
+	client := *fp.client
+	client.Timeout = fp.timeout
+	client.CheckRedirect = fp.checkRedirect
+	if t := fp.transport(client.Transport); t != nil {
+		client.Transport = t
+	}
+	fp.client = &client
+	return fp
+}
+
+// transport checks the address of every connection, without proxies as they would resolve the hosts
+func (fp *FileProvider) transport(rt http.RoundTripper) *http.Transport {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	t = t.Clone()
+	t.Proxy = nil
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: fp.checkAddress}
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+func (fp *FileProvider) File(ctx context.Context, url string) (io.Reader, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, feed.NewError(feed.ErrCodeInvalidFileUrl, err)
+	}
+	if err := fp.checkHost(req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	fp.authorize(req)
+
+	resp, err := fp.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var redirects *redirectError
+		if errors.As(err, &redirects) {
+			return nil, ErrTooManyRedirects(fp.maxRedirects)
+		}
+		// a refused host or address
+		var refused *feed.Error
+		if errors.As(err, &refused) {
+			return nil, refused
+		}
+		return nil, ErrRequestingFile(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ErrDownloadingFile(url, resp.StatusCode)
+	}
+	if resp.ContentLength > fp.maxSize {
+		return nil, ErrFileTooLarge(fp.maxSize)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, fp.maxSize+1))
+	if err != nil {
+		return nil, ErrRequestingFile(err)
+	}
+	if int64(len(b)) > fp.maxSize {
+		return nil, ErrFileTooLarge(fp.maxSize)
+	}
+	return bytes.NewReader(b), nil
+}
+
+func (fp *FileProvider) Name() string {
+	return "http"
+}
+
+type redirectError struct{}
+
+func (e *redirectError) Error() string {
+	return "too many redirects"
+}
+
+// checkRedirect limits the redirects and sends the host headers to the new location
+func (fp *FileProvider) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= fp.maxRedirects {
+		return &redirectError{}
+	}
+	if err := fp.checkHost(req.URL.Hostname()); err != nil {
+		return err
+	}
+	for name := range via[0].Header {
+		if fp.isHostHeader(via[0].URL.Hostname(), name) {
+			req.Header.Del(name)
+		}
+	}
+	fp.authorize(req)
+	return nil
+}
+
+func (fp *FileProvider) authorize(req *http.Request) {
+	for name, values := range fp.headers[strings.ToLower(req.URL.Hostname())] {
+		req.Header[name] = values
+	}
+}
+
+func (fp *FileProvider) isHostHeader(host, name string) bool {
+	_, ok := fp.headers[strings.ToLower(host)][name]
+	return ok
+}
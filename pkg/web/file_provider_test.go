@@ -0,0 +1,142 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+	"github.com/amelendres/go-feeder/pkg/web"
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/doc.docx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "document")
+	})
+	mux.HandleFunc("/private.docx", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "private document")
+	})
+	mux.HandleFunc("/large.docx", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, strings.Repeat("a", 64))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/doc.docx", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	mux.HandleFunc("/localhost", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+strings.Split(r.Host, ":")[1]+"/doc.docx", http.StatusFound)
+	})
+	mux.HandleFunc("/slow.docx", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprint(w, "slow document")
+	})
+	mux.HandleFunc("/down.docx", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func read(t *testing.T, fp feed.FileProvider, url string) (string, error) {
+	t.Helper()
+	r, err := fp.File(context.Background(), url)
+	if err != nil {
+		return "", err
+	}
+	b, _ := ioutil.ReadAll(r)
+	return string(b), nil
+}
+
+func TestFileProvider_File(t *testing.T) {
+	srv := newServer(t)
+	host := strings.Split(strings.TrimPrefix(srv.URL, "http://"), ":")[0]
+
+	t.Run("it downloads the file following redirects", func(t *testing.T) {
+		content, err := read(t, web.NewFileProvider(web.WithPrivateAddresses()), srv.URL+"/moved")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "document", content)
+	})
+
+	t.Run("it sends the headers of the host", func(t *testing.T) {
+		content, err := read(t, web.NewFileProvider(web.WithPrivateAddresses(), web.WithHeader(host, "Authorization", "Bearer secret")), srv.URL+"/private.docx")
+		assert.Nil(t, err)
+		assert.Equal(t, "private document", content)
+
+		_, err = read(t, web.NewFileProvider(web.WithPrivateAddresses(), web.WithHeader("cms.example.org", "Authorization", "Bearer secret")), srv.URL+"/private.docx")
+		assert.Equal(t, feed.ErrCodeUnreadableFile, feed.Code(err))
+	})
+
+	t.Run("it classifies the failures", func(t *testing.T) {
+		fp := web.NewFileProvider(web.WithPrivateAddresses(), web.WithMaxSize(32), web.WithMaxRedirects(3), web.WithTimeout(50*time.Millisecond))
+
+		for path, code := range map[string]feed.ErrorCode{
+			"/not-found.docx": feed.ErrCodeFileNotFound,
+			"/down.docx":      feed.ErrCodeUnavailable,
+			"/large.docx":     feed.ErrCodeFileTooLarge,
+			"/loop":           feed.ErrCodeInvalidFileUrl,
+			"/slow.docx":      feed.ErrCodeUnavailable,
+		} {
+			_, err := read(t, fp, srv.URL+path)
+			assert.Equal(t, code, feed.Code(err), path)
+		}
+	})
+
+	t.Run("it stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := web.NewFileProvider(web.WithPrivateAddresses()).File(ctx, srv.URL+"/doc.docx")
+
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("it refuses the private addresses by default", func(t *testing.T) {
+		for _, url := range []string{srv.URL + "/doc.docx", srv.URL + "/localhost", "http://localhost/doc.docx", "http://[::1]/doc.docx"} {
+			_, err := read(t, web.NewFileProvider(), url)
+			assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err), url)
+		}
+	})
+
+	t.Run("it refuses the link-local and reserved addresses, even redirected to", func(t *testing.T) {
+		fp := web.NewFileProvider(web.WithPrivateAddresses())
+
+		for _, url := range []string{srv.URL + "/metadata", "http://169.254.169.254/latest/meta-data/", "http://0.0.0.0/doc.docx", "http://[::ffff:169.254.169.254]/"} {
+			_, err := read(t, fp, url)
+			assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err), url)
+		}
+	})
+
+	t.Run("it downloads only from the allowed hosts", func(t *testing.T) {
+		fp := web.NewFileProvider(append(web.HostOptions(" cms.example.org, "+host), web.WithPrivateAddresses())...)
+
+		content, err := read(t, fp, srv.URL+"/moved")
+		assert.Nil(t, err)
+		assert.Equal(t, "document", content)
+
+		_, err = read(t, fp, srv.URL+"/localhost")
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+
+		_, err = read(t, web.NewFileProvider(web.WithAllowedHosts("cms.example.org"), web.WithPrivateAddresses()), srv.URL+"/doc.docx")
+		assert.Equal(t, feed.ErrCodeInvalidFileUrl, feed.Code(err))
+	})
+}
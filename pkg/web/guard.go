@@ -0,0 +1,93 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	feed "github.com/amelendres/go-feeder/pkg"
+)
+
+var (
+	ErrHostNotAllowed = func(host string) error {
+		return feed.NewError(feed.ErrCodeInvalidFileUrl, fmt.Errorf("host <%s> is not allowed", host))
+	}
+	ErrAddressNotAllowed = func(address string) error {
+		return feed.NewError(feed.ErrCodeInvalidFileUrl, fmt.Errorf("address <%s> is not public", address))
+	}
+)
+
+// privateNetworks are the loopback and private ranges of the intranet servers
+var privateNetworks = networks(
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"::1/128", "fc00::/7",
+)
+
+// reservedNetworks are never downloaded from: the link-local ranges, as the cloud metadata 169.254.169.254,
+// the unspecified, shared, documentation, benchmarking, multicast and reserved ones
+var reservedNetworks = networks(
+	"0.0.0.0/8", "100.64.0.0/10", "169.254.0.0/16", "192.0.0.0/24", "192.0.2.0/24", "198.18.0.0/15",
+	"198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "100::/64", "2001:db8::/32", "fe80::/10", "ff00::/8",
+)
+
+func networks(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostOptions parses the comma separated allowed hosts of the spec, as "cms.example.org,cloud.example.org"
+func HostOptions(spec string) []Option {
+	var hosts []string
+	for _, host := range strings.Split(spec, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	return []Option{WithAllowedHosts(hosts...)}
+}
+
+// checkHost fails with a host out of the allowed ones, any host is allowed without them
+func (fp *FileProvider) checkHost(host string) error {
+	if len(fp.allowedHosts) > 0 && !fp.allowedHosts[strings.ToLower(host)] {
+		return ErrHostNotAllowed(host)
+	}
+	return nil
+}
+
+// checkAddress fails connecting to a non public address, it is called with the resolved address
+// of every connection, so the redirects and the DNS rebinding are covered
+func (fp *FileProvider) checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrAddressNotAllowed(address)
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil, contains(reservedNetworks, ip):
+		return ErrAddressNotAllowed(host)
+	case !fp.private && contains(privateNetworks, ip):
+		return ErrAddressNotAllowed(host)
+	}
+	return nil
+}